@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// sshClientConfig builds the SSH connection settings for an inventory host
+func sshClientConfig(host *config.Host) ssh.ClientConfig {
+	cfg := ssh.ClientConfig{
+		Host:            host.Address,
+		Port:            host.Port,
+		Username:        host.Username,
+		PrivateKey:      host.PrivateKey,
+		HostKey:         host.HostKey,
+		KnownHosts:      host.KnownHosts,
+		TrustOnFirstUse: host.TrustOnFirstUse,
+
+		Certificate:       host.Certificate,
+		PassphraseEnv:     host.PassphraseEnv,
+		PassphraseCommand: host.PassphraseCommand,
+		AuthOrder:         host.AuthOrder,
+
+		Timeout: commandTimeout,
+	}
+	if host.Passphrase != "" {
+		passphrase := host.Passphrase
+		cfg.Passphrase = func() ([]byte, error) {
+			value, err := passphrase.Resolve()
+			return []byte(value), err
+		}
+	}
+	for i := range host.Jump {
+		cfg.Jump = append(cfg.Jump, sshClientConfig(&host.Jump[i].Host))
+	}
+	return cfg
+}
+
+var (
+	poolOnce sync.Once
+	pool     *ssh.Pool
+
+	// maxSessions limits concurrent sessions per host, set with --max-sessions
+	maxSessions int
+)
+
+// connectionPool returns the pool shared by every command of this invocation
+func connectionPool() *ssh.Pool {
+	poolOnce.Do(func() {
+		pool = ssh.NewPool(ssh.PoolOptions{MaxSessions: maxSessions})
+	})
+	return pool
+}
+
+// closeConnections closes the pooled connections, if any were opened
+func closeConnections() {
+	if pool != nil {
+		pool.Close()
+	}
+}
+
+// connect returns a client for an inventory host. Clients for the same host
+// share one pooled SSH connection. ctx bounds connecting only.
+func connect(ctx context.Context, host *config.Host) (ssh.Client, error) {
+	return connectionPool().Get(ctx, sshClientConfig(host))
+}
+
+// podmanBackend connects to an inventory host and returns the podman backend
+// selected by its podman_backend setting
+func podmanBackend(ctx context.Context, host *config.Host) (podman.Backend, error) {
+	client, err := connect(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to host: %w", err)
+	}
+	return podman.NewBackend(ctx, host.PodmanBackend, client, host.PodmanSocket)
+}
+
+// registryLogin logs podman on host in to the registry of image when the
+// inventory has credentials for it
+func registryLogin(ctx context.Context, host *config.Host, image string) error {
+	registry := podman.Registry(image)
+	username, password, err := registryCredentials(host, registry)
+	if err != nil || username == "" {
+		return err
+	}
+
+	client, err := connect(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to connect to host: %w", err)
+	}
+	if err := podman.Login(ctx, client, registry, username, password); err != nil {
+		return fmt.Errorf("failed to log in to %s: %w", registry, err)
+	}
+	return nil
+}
+
+// registryCredentials resolves the credentials of host for registry. The
+// username is empty if there are none.
+func registryCredentials(host *config.Host, registry string) (username, password string, err error) {
+	credentials, ok := host.Registries[registry]
+	if !ok || credentials == "" {
+		return "", "", nil
+	}
+	value, err := credentials.Resolve()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve credentials for %s: %w", registry, err)
+	}
+	username, password, ok = strings.Cut(value, ":")
+	if !ok || username == "" {
+		return "", "", fmt.Errorf("credentials for %s are not in the form username:password", registry)
+	}
+	return username, password, nil
+}
+
+// commandTimeout overrides the default timeout of remote commands when set
+// with --timeout
+var commandTimeout time.Duration
+
+// commandContext returns the context for a remote command, bounded by
+// --timeout or defaultTimeout
+func commandContext(defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout := defaultTimeout
+	if commandTimeout > 0 {
+		timeout = commandTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// TestConnect_ContextTimeout verifies that connecting to a host whose sshd
+// never answers gives up when the command's context ends
+func TestConnect_ContextTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	hostKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+
+	defer func() {
+		closeConnections()
+		pool, poolOnce = nil, sync.Once{}
+	}()
+
+	host := &config.Host{
+		Name:       "hung",
+		Address:    "127.0.0.1",
+		Port:       listener.Addr().(*net.TCPAddr).Port,
+		Username:   "test",
+		PrivateKey: keyPath,
+		HostKey:    string(ssh.MarshalAuthorizedKey(hostKey)),
+		AuthOrder:  []string{"key"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := connect(ctx, host); err == nil {
+		t.Fatal("connect should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("connect should give up with the context, took %v", elapsed)
+	}
+}
@@ -1,11 +1,21 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
-	
+	"time"
+
+	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/cmd/internal/test"
+	"github.com/ytnobody/podman-swarm/pkg/config"
 )
 
 // Test inspect command argument validation
@@ -205,3 +215,274 @@ func TestMockConfig_GetHostOrGroup(t *testing.T) {
 		t.Errorf("GetHostOrGroup should return 2 hosts for 'all' group, got: %d", len(hosts))
 	}
 }
+
+// Test MockSSHClient streaming falls back to ExecuteFunc
+func TestMockSSHClient_Stream(t *testing.T) {
+	client := &test.MockSSHClient{
+		ExecuteFunc: func(ctx context.Context, cmd string) (string, error) {
+			return "log line\n", nil
+		},
+	}
+
+	var stdout bytes.Buffer
+	if err := client.Stream(context.Background(), "podman logs web", nil, &stdout, io.Discard); err != nil {
+		t.Errorf("stream should not error, got: %v", err)
+	}
+	if stdout.String() != "log line\n" {
+		t.Errorf("stream output mismatch, got: %q", stdout.String())
+	}
+}
+
+// Test logs command construction
+func TestLogsCommand(t *testing.T) {
+	if got := logsCommand("web", false, -1); got != "podman logs web" {
+		t.Errorf("unexpected command: %s", got)
+	}
+	if got := logsCommand("web", true, 100); got != "podman logs --follow --tail 100 web" {
+		t.Errorf("unexpected command: %s", got)
+	}
+}
+
+// Test line prefixing of concurrent host output
+func TestLinePrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	w := newLinePrefixWriter(&mu, &out, "[host1] ")
+
+	fmt.Fprint(w, "first\nsec")
+	fmt.Fprint(w, "ond\npartial")
+	w.Flush()
+
+	expected := "[host1] first\n[host1] second\n[host1] partial\n"
+	if out.String() != expected {
+		t.Errorf("output mismatch, expected: %q, got: %q", expected, out.String())
+	}
+}
+
+// Test exec command construction with interactive flags
+func TestExecCommand(t *testing.T) {
+	got := execCommand([]string{"-i", "-t"}, "web", []string{"/bin/sh"})
+	if got != "podman exec -i -t web /bin/sh" {
+		t.Errorf("unexpected command: %s", got)
+	}
+
+	got = execCommand(nil, "web", []string{"sh", "-c", "echo $HOSTNAME"})
+	if got != "podman exec web sh -c 'echo $HOSTNAME'" {
+		t.Errorf("arguments should be quoted, got: %s", got)
+	}
+}
+
+// Test exit errors without a message are silent
+func TestExitError(t *testing.T) {
+	err := error(&exitError{code: 3})
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != 3 {
+		t.Errorf("exit error should carry its code, got: %v", err)
+	}
+	if exitErr.err != nil {
+		t.Error("exit error without message should not wrap an error")
+	}
+}
+
+// Test fan-out results are ordered by host name regardless of completion order
+func TestFanOut_Order(t *testing.T) {
+	hosts := []*config.Host{{Name: "host3"}, {Name: "host1"}, {Name: "host2"}}
+	delays := map[string]time.Duration{"host1": 30 * time.Millisecond, "host2": 0, "host3": 10 * time.Millisecond}
+
+	results := fanOutN(hosts, 0, false, func(host *config.Host) (string, error) {
+		time.Sleep(delays[host.Name])
+		return host.Name, nil
+	})
+
+	for i, want := range []string{"host1", "host2", "host3"} {
+		if results[i].Host.Name != want || results[i].Value != want {
+			t.Errorf("result %d: expected %s, got: %s (%s)", i, want, results[i].Host.Name, results[i].Value)
+		}
+	}
+}
+
+// Test fan-out runs at most the given number of hosts at once
+func TestFanOut_Limit(t *testing.T) {
+	var hosts []*config.Host
+	for i := 0; i < 8; i++ {
+		hosts = append(hosts, &config.Host{Name: fmt.Sprintf("host%d", i)})
+	}
+
+	var running, peak int32
+	fanOutN(hosts, 3, false, func(host *config.Host) (struct{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return struct{}{}, nil
+	})
+
+	if peak != 3 {
+		t.Errorf("expected at most 3 concurrent hosts, got: %d", peak)
+	}
+}
+
+// Test fail-fast skips the hosts after the first failure
+func TestFanOut_FailFast(t *testing.T) {
+	hosts := []*config.Host{{Name: "host1"}, {Name: "host2"}, {Name: "host3"}}
+
+	var calls int32
+	results := fanOutN(hosts, 1, true, func(host *config.Host) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		if host.Name == "host2" {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	})
+
+	if calls != 2 {
+		t.Errorf("expected 2 hosts to run, got: %d", calls)
+	}
+	if results[0].Err != nil || results[1].Err == nil || !errors.Is(results[2].Err, errSkipped) {
+		t.Errorf("unexpected results: %v, %v, %v", results[0].Err, results[1].Err, results[2].Err)
+	}
+}
+
+// Test exit statuses of multi-host operations
+func TestSummarize(t *testing.T) {
+	host1, host2 := &config.Host{Name: "host1"}, &config.Host{Name: "host2"}
+	failure := errors.New("boom")
+
+	testCases := []struct {
+		name     string
+		results  []hostResult[string]
+		expected int
+	}{
+		{"all succeeded", []hostResult[string]{{Host: host1}, {Host: host2}}, 0},
+		{"some failed", []hostResult[string]{{Host: host1}, {Host: host2, Err: failure}}, exitSomeFailed},
+		{"all failed", []hostResult[string]{{Host: host1, Err: failure}, {Host: host2, Err: errSkipped}}, exitAllFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := summarize(tc.results)
+			code := 0
+			var exitErr *exitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.code
+			} else if err != nil {
+				t.Fatalf("expected an exit error, got: %v", err)
+			}
+			if code != tc.expected {
+				t.Errorf("expected exit status %d, got: %d", tc.expected, code)
+			}
+		})
+	}
+}
+
+// Test unknown targets exit with a distinct status
+func TestTargetHosts_NotFound(t *testing.T) {
+	_, err := targetHosts(test.MockConfig(), "nonexistent")
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != exitNotFound {
+		t.Errorf("expected exit status %d, got: %v", exitNotFound, err)
+	}
+
+	hosts, err := targetHosts(test.MockConfig(), "all")
+	if err != nil || len(hosts) != 2 {
+		t.Errorf("expected 2 hosts in group all, got: %d, %v", len(hosts), err)
+	}
+}
+
+// Test the inventory is chosen from --config, --context, the environment
+// and the current context in that order
+func TestUseContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PODMAN_SWARM_CONFIG", "")
+	defer func() {
+		configFile, contextName = "", ""
+		useContext()
+	}()
+
+	contexts := &config.Contexts{Current: "staging", Contexts: []config.Context{
+		{Name: "staging", Config: "/staging.yaml", Selector: "web"},
+		{Name: "prod", Config: "/prod.yaml", Output: config.OutputJSON},
+	}}
+	if err := contexts.Save(); err != nil {
+		t.Fatalf("failed to save contexts: %v", err)
+	}
+
+	tests := []struct {
+		config, context, env string
+		path, active         string
+	}{
+		{"", "", "", "/staging.yaml", "staging"},
+		{"", "", "/env.yaml", "/env.yaml", ""},
+		{"", "prod", "/env.yaml", "/prod.yaml", "prod"},
+		{"/flag.yaml", "", "", "/flag.yaml", ""},
+		{"/flag.yaml", "prod", "", "/flag.yaml", "prod"},
+	}
+	for _, tc := range tests {
+		configFile, contextName = tc.config, tc.context
+		t.Setenv("PODMAN_SWARM_CONFIG", tc.env)
+		if err := useContext(); err != nil {
+			t.Fatalf("useContext should not fail: %v", err)
+		}
+		if path := config.ConfigPath(); path != tc.path {
+			t.Errorf("%+v: expected inventory %s, got %s", tc, tc.path, path)
+		}
+		active := ""
+		if activeContext != nil {
+			active = activeContext.Name
+		}
+		if active != tc.active {
+			t.Errorf("%+v: expected context %q, got %q", tc, tc.active, active)
+		}
+	}
+
+	configFile, contextName = "", "nonexistent"
+	var exitErr *exitError
+	if err := useContext(); !errors.As(err, &exitErr) || exitErr.code != exitNotFound {
+		t.Errorf("expected exit status %d, got: %v", exitNotFound, err)
+	}
+}
+
+// Test listings exit with the multi-host status when hosts cannot be
+// listed
+func TestListings_ExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.yaml")
+	inventory := fmt.Sprintf(`
+hosts:
+  - name: down1
+    address: 127.0.0.1
+    port: 1
+    private_key: %[1]s/missing
+    auth_order: [key]
+  - name: down2
+    address: 127.0.0.1
+    port: 1
+    private_key: %[1]s/missing
+    auth_order: [key]
+`, dir)
+	if err := os.WriteFile(path, []byte(inventory), 0600); err != nil {
+		t.Fatalf("failed to write inventory: %v", err)
+	}
+	config.SetConfigPath(path)
+	saved := activeContext
+	activeContext = nil
+	defer func() {
+		config.SetConfigPath("")
+		activeContext = saved
+		closeConnections()
+		pool, poolOnce = nil, sync.Once{}
+	}()
+
+	for _, cmd := range []*cobra.Command{psCmd, statusCmd, imageLsCmd, volumeLsCmd, podLsCmd, networkLsCmd} {
+		err := cmd.RunE(cmd, nil)
+		var exitErr *exitError
+		if !errors.As(err, &exitErr) || exitErr.code != exitAllFailed {
+			t.Errorf("%s: expected exit status %d, got: %v", cmd.CommandPath(), exitAllFailed, err)
+		}
+	}
+}
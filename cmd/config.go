@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the inventory configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the inventory for problems",
+	Long: `Check the inventory for errors, e.g. duplicate host names, invalid ports or groups
+referring to unknown hosts, and warnings, e.g. unknown keys or missing key files.
+Exits with status 1 if errors are found, or warnings with --strict.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		strict, _ := cmd.Flags().GetBool("strict")
+		cmd.SilenceUsage = true
+
+		problems, err := config.Validate()
+		if err != nil {
+			return err
+		}
+
+		errs, warnings := 0, 0
+		for _, p := range problems {
+			fmt.Println(p)
+			if p.Severity == config.Error {
+				errs++
+			} else {
+				warnings++
+			}
+		}
+
+		if len(problems) == 0 {
+			fmt.Println("Configuration is valid")
+			return nil
+		}
+		fmt.Printf("%d errors, %d warnings\n", errs, warnings)
+		if errs > 0 || (strict && warnings > 0) {
+			return &exitError{code: 1}
+		}
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show <host>",
+	Short: "Display the effective settings of a host",
+	Long: `Display the settings of a host after applying group vars and defaults,
+and where each setting came from.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		settings, err := cfg.Settings(args[0])
+		if err != nil {
+			return &exitError{code: exitNotFound, err: err}
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Setting", "Value", "Source"})
+		table.SetBorder(true)
+		table.SetRowLine(false)
+		table.SetAutoWrapText(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		for _, s := range settings {
+			table.Append([]string{s.Key, s.Value, s.Origin})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("strict", false, "Fail on warnings as well as errors")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+var (
+	// configFile is the inventory given with --config
+	configFile string
+	// contextName is the context given with --context
+	contextName string
+	// activeContext is the context in use, if any
+	activeContext *config.Context
+)
+
+// useContext selects the inventory to load. --config wins over --context,
+// which wins over $PODMAN_SWARM_CONFIG, which wins over the current context.
+func useContext() error {
+	activeContext = nil
+	config.SetConfigPath(configFile)
+
+	name := contextName
+	if name == "" && (configFile != "" || os.Getenv("PODMAN_SWARM_CONFIG") != "") {
+		return nil
+	}
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if contexts.Current == "" {
+			return nil
+		}
+		ctx, err := contexts.Get(contexts.Current)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: current %v\n", err)
+			return nil
+		}
+		activeContext = ctx
+	} else {
+		ctx, err := contexts.Get(name)
+		if err != nil {
+			return &exitError{code: exitNotFound, err: err}
+		}
+		activeContext = ctx
+	}
+
+	if configFile == "" {
+		config.SetConfigPath(activeContext.Config)
+	}
+	return nil
+}
+
+// hostSelector returns the selector given with --hosts, or the one of the
+// active context
+func hostSelector(cmd *cobra.Command) string {
+	if selector, _ := cmd.Flags().GetString("hosts"); selector != "" {
+		return selector
+	}
+	if activeContext != nil {
+		return activeContext.Selector
+	}
+	return ""
+}
+
+// jsonOutput reports whether to print JSON, as given with --json or else by
+// the output format of the active context
+func jsonOutput(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("json") {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		return jsonOutput
+	}
+	return activeContext != nil && activeContext.Output == config.OutputJSON
+}
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage named inventories",
+	Long: `Manage contexts, named inventories with a default host selector and output format,
+stored in ~/.config/podman-swarm/contexts.yaml. Use --context to use a context for one
+command, or "context use" to make it the default.`,
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List contexts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			return err
+		}
+		if len(contexts.Contexts) == 0 {
+			fmt.Println("No contexts defined")
+			return nil
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Current", "Name", "Inventory", "Selector", "Output"})
+		table.SetBorder(true)
+		table.SetRowLine(false)
+		table.SetAutoWrapText(false)
+		for _, ctx := range contexts.Contexts {
+			current := ""
+			if ctx.Name == contexts.Current {
+				current = "*"
+			}
+			table.Append([]string{current, ctx.Name, ctx.Config, ctx.Selector, ctx.Output})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <name> --config <inventory>",
+	Short: "Create or update a context",
+	Long: `Create a context for the inventory given with --config, or update an existing one.
+Use --hosts to limit commands to hosts matching a selector unless they are given one,
+and --output json to print JSON by default.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selector, _ := cmd.Flags().GetString("hosts")
+		output, _ := cmd.Flags().GetString("output")
+		cmd.SilenceUsage = true
+
+		if configFile == "" {
+			return fmt.Errorf("--config is required")
+		}
+		// Keep paths under ~ portable between machines
+		inventory := configFile
+		if !strings.HasPrefix(inventory, "~") {
+			var err error
+			if inventory, err = filepath.Abs(inventory); err != nil {
+				return err
+			}
+		}
+		ctx := config.Context{Name: args[0], Config: inventory, Selector: selector, Output: output}
+		if err := ctx.Validate(); err != nil {
+			return err
+		}
+
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			return err
+		}
+		contexts.Set(ctx)
+		if err := contexts.Save(); err != nil {
+			return fmt.Errorf("failed to save contexts: %w", err)
+		}
+		fmt.Printf("Context %s saved\n", ctx.Name)
+		return nil
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a context the default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			return err
+		}
+		if _, err := contexts.Get(args[0]); err != nil {
+			return &exitError{code: exitNotFound, err: err}
+		}
+		contexts.Current = args[0]
+		if err := contexts.Save(); err != nil {
+			return fmt.Errorf("failed to save contexts: %w", err)
+		}
+		fmt.Printf("Switched to context %s\n", args[0])
+		return nil
+	},
+}
+
+var contextShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Display a context, by default the one in use",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		ctx := activeContext
+		if len(args) == 1 {
+			contexts, err := config.LoadContexts()
+			if err != nil {
+				return err
+			}
+			if ctx, err = contexts.Get(args[0]); err != nil {
+				return &exitError{code: exitNotFound, err: err}
+			}
+		}
+		if ctx == nil {
+			fmt.Printf("No context in use; inventory is %s\n", config.ConfigPath())
+			return nil
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Setting", "Value"})
+		table.SetBorder(true)
+		table.SetRowLine(false)
+		table.SetAutoWrapText(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.Append([]string{"name", ctx.Name})
+		table.Append([]string{"config", ctx.Config})
+		table.Append([]string{"selector", ctx.Selector})
+		table.Append([]string{"output", ctx.Output})
+		table.Render()
+		return nil
+	},
+}
+
+var contextRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Delete a context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			return err
+		}
+		if err := contexts.Remove(args[0]); err != nil {
+			return &exitError{code: exitNotFound, err: err}
+		}
+		if err := contexts.Save(); err != nil {
+			return fmt.Errorf("failed to save contexts: %w", err)
+		}
+		fmt.Printf("Context %s deleted\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	contextAddCmd.Flags().StringP("hosts", "l", "", "Default host selector of the context")
+	contextAddCmd.Flags().String("output", "", "Default output format of the context (table or json)")
+
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextAddCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextShowCmd)
+	contextCmd.AddCommand(contextRmCmd)
+	RootCmd.AddCommand(contextCmd)
+}
@@ -2,24 +2,35 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
 	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	"golang.org/x/term"
 )
 
 var execCmd = &cobra.Command{
 	Use:   "exec <host> <cid/name> <cmd>",
 	Short: "Execute commands inside containers",
-	Long:  `Execute podman exec command remotely on specified host.`,
-	Args:  cobra.MinimumNArgs(3),
+	Long: `Execute podman exec command remotely on specified host.
+Use -i to attach stdin and -t to allocate a terminal, e.g. "exec -it host1 web /bin/sh"
+for an interactive shell. The exit status of the command is returned as podman-swarm's exit status.`,
+	Args: cobra.MinimumNArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostName := args[0]
 		containerID := args[1]
 		command := args[2:]
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		tty, _ := cmd.Flags().GetBool("tty")
+
+		cmd.SilenceUsage = true
 
 		cfg, err := config.Load()
 		if err != nil {
@@ -28,30 +39,149 @@ var execCmd = &cobra.Command{
 
 		host := cfg.GetHostByName(hostName)
 		if host == nil {
-			return fmt.Errorf("host '%s' not found", hostName)
+			return &exitError{code: exitNotFound, err: fmt.Errorf("host '%s' not found", hostName)}
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		connectCtx, cancelConnect := commandContext(30 * time.Second)
+		defer cancelConnect()
 
-		client, err := ssh.NewClient(ssh.ClientConfig{
-			Host:       host.Address,
-			Port:       host.Port,
-			Username:   host.Username,
-			PrivateKey: host.PrivateKey,
-		})
+		client, err := connect(connectCtx, host)
 		if err != nil {
 			return fmt.Errorf("failed to connect to host: %w", err)
 		}
 		defer client.Close()
 
-		cmdStr := fmt.Sprintf("podman exec %s %s", containerID, strings.Join(command, " "))
-		output, err := client.Execute(ctx, cmdStr)
-		if err != nil {
-			return err
+		var podmanFlags []string
+		if interactive {
+			podmanFlags = append(podmanFlags, "-i")
+		}
+		if tty {
+			podmanFlags = append(podmanFlags, "-t")
+		}
+		cmdStr := execCommand(podmanFlags, containerID, command)
+
+		if interactive || tty {
+			err = execInteractive(client, cmdStr, interactive, tty)
+		} else {
+			ctx, cancel := commandContext(30 * time.Second)
+			defer cancel()
+			err = client.Stream(ctx, cmdStr, nil, os.Stdout, os.Stderr)
 		}
 
-		fmt.Print(output)
-		return nil
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) && exitErr.Kind == ssh.RemoteFailure {
+			return &exitError{code: exitErr.ExitCode}
+		}
+		return err
 	},
 }
+
+func init() {
+	execCmd.Flags().BoolP("interactive", "i", false, "Keep stdin attached to the command")
+	execCmd.Flags().BoolP("tty", "t", false, "Allocate a pseudo-terminal")
+	// Flags after the container name belong to the remote command
+	execCmd.Flags().SetInterspersed(false)
+}
+
+func execCommand(podmanFlags []string, containerID string, command []string) string {
+	args := append([]string{"exec"}, podmanFlags...)
+	args = append(args, containerID)
+	args = append(args, command...)
+	return podman.Command(args...)
+}
+
+// execInteractive runs cmdStr attached to the local terminal. With tty the
+// local terminal is put into raw mode and its size changes are forwarded;
+// otherwise interrupt signals are forwarded to the remote command.
+func execInteractive(client ssh.Client, cmdStr string, interactive, tty bool) error {
+	ctx := context.Background()
+	if commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+
+	opts := ssh.InteractiveOptions{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if interactive {
+		opts.Stdin = os.Stdin
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if tty {
+		if !term.IsTerminal(stdinFd) {
+			return errors.New("the input device is not a TTY; remove -t")
+		}
+
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		resize, stopResize := watchWindowSize(int(os.Stdout.Fd()))
+		defer stopResize()
+
+		termName := os.Getenv("TERM")
+		if termName == "" {
+			termName = "xterm"
+		}
+		opts.Terminal = &ssh.Terminal{
+			Term:   termName,
+			Size:   ssh.WindowSize{Width: width, Height: height},
+			Resize: resize,
+		}
+
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(stdinFd, state)
+
+		// SIGTERM and SIGHUP would leave the terminal in raw mode; restore
+		// it and end the session instead
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(sigs)
+		go func() {
+			select {
+			case <-sigs:
+				term.Restore(stdinFd, state)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	} else {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+
+		forward := make(chan string, 1)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case sig := <-sigs:
+					name := "INT"
+					if sig == syscall.SIGTERM {
+						name = "TERM"
+					}
+					select {
+					case forward <- name:
+					case <-done:
+						return
+					}
+				}
+			}
+		}()
+		opts.Signals = forward
+	}
+
+	return client.Interactive(ctx, cmdStr, opts)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+// Exit statuses of podman-swarm. Other failures exit with 1; exec exits with
+// the status of the remote command.
+const (
+	// exitSomeFailed means the operation failed on some of the target hosts
+	exitSomeFailed = 2
+	// exitAllFailed means the operation succeeded on none of the target hosts
+	exitAllFailed = 3
+	// exitNotFound means the target host or group is not in the inventory
+	exitNotFound = 4
+)
+
+// exitError makes podman-swarm exit with a specific status. When err is nil
+// nothing is printed.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// notFound makes errors about unknown hosts and groups exit with
+// exitNotFound
+func notFound(err error) error {
+	var notFound *config.NotFoundError
+	if errors.As(err, &notFound) {
+		return &exitError{code: exitNotFound, err: err}
+	}
+	return err
+}
+
+// Execute runs the root command and returns the process exit status
+func Execute() int {
+	err := RootCmd.Execute()
+	closeConnections()
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		if exitErr.err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", exitErr.err)
+		}
+		return exitErr.code
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return 1
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+// defaultParallel is the default number of hosts operated on at once
+const defaultParallel = 10
+
+var (
+	// parallel limits how many hosts are operated on at once, set with
+	// --parallel. 0 means no limit.
+	parallel int
+	// failFast skips the remaining hosts after the first failure, set with
+	// --fail-fast
+	failFast bool
+)
+
+// errSkipped is the error of hosts not attempted because of --fail-fast
+var errSkipped = errors.New("skipped after an earlier failure")
+
+// hostResult is the outcome of an operation on one host
+type hostResult[T any] struct {
+	Host  *config.Host
+	Value T
+	Err   error
+}
+
+// fanOut runs fn on every host as limited by --parallel and --fail-fast
+func fanOut[T any](hosts []*config.Host, fn func(host *config.Host) (T, error)) []hostResult[T] {
+	return fanOutN(hosts, parallel, failFast, fn)
+}
+
+// fanOutN runs fn on every host with at most limit hosts at a time (no limit
+// if limit <= 0) and returns the results ordered by host name, regardless of
+// completion order. With stopOnError, hosts not yet started when a host
+// fails are skipped with errSkipped; hosts already running are not
+// interrupted.
+func fanOutN[T any](hosts []*config.Host, limit int, stopOnError bool, fn func(host *config.Host) (T, error)) []hostResult[T] {
+	sorted := append([]*config.Host{}, hosts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	if limit <= 0 || limit > len(sorted) {
+		limit = len(sorted)
+	}
+
+	results := make([]hostResult[T], len(sorted))
+	slots := make(chan struct{}, limit)
+	var failed bool
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, host := range sorted {
+		results[i].Host = host
+		slots <- struct{}{}
+
+		mu.Lock()
+		skip := stopOnError && failed
+		mu.Unlock()
+		if skip {
+			<-slots
+			results[i].Err = errSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *hostResult[T]) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			r.Value, r.Err = fn(r.Host)
+			if r.Err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// sortHostResults orders results by host name, as returned by fanOut
+func sortHostResults[T any](results []hostResult[T]) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Host.Name < results[j].Host.Name
+	})
+}
+
+// listStatus sets the error of every host whose listing failed, for
+// summarize. Listings fan out with functions that do not fail, so that
+// --fail-fast never leaves a host out, and carry errors in their results.
+func listStatus[T any](results []hostResult[T], listErr func(T) string) []hostResult[T] {
+	for i := range results {
+		if msg := listErr(results[i].Value); msg != "" {
+			results[i].Err = errors.New(msg)
+		}
+	}
+	return results
+}
+
+// printHostResults prints the output of every host, or its error to stderr
+func printHostResults(results []hostResult[string]) {
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error on %s: %v\n", r.Host.Name, r.Err)
+			continue
+		}
+		fmt.Printf("[%s] %s\n", r.Host.Name, r.Value)
+	}
+}
+
+// targetHosts resolves a host selector, e.g. a host or group name, to its
+// hosts
+func targetHosts(cfg *config.Config, selector string) ([]*config.Host, error) {
+	hosts, err := cfg.Select(selector)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return hosts, nil
+}
+
+// summarize writes a table of succeeded and failed hosts to stderr and
+// returns the exit error for results, or nil if every host succeeded
+func summarize[T any](results []hostResult[T]) error {
+	table := tablewriter.NewWriter(os.Stderr)
+	table.SetHeader([]string{"Host", "Result", "Details"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err == nil:
+			table.Append([]string{r.Host.Name, "OK", ""})
+		case errors.Is(r.Err, errSkipped):
+			failed++
+			table.Append([]string{r.Host.Name, "SKIPPED", r.Err.Error()})
+		default:
+			failed++
+			table.Append([]string{r.Host.Name, "FAILED", r.Err.Error()})
+		}
+	}
+	table.Render()
+
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(results):
+		return &exitError{code: exitAllFailed, err: fmt.Errorf("failed on all %d hosts", len(results))}
+	default:
+		return &exitError{code: exitSomeFailed, err: fmt.Errorf("failed on %d of %d hosts", failed, len(results))}
+	}
+}
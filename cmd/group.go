@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Add and change inventory groups",
+	Long: `Edit the groups of the inventory file. Comments and the order of entries are kept,
+and the inventory is validated before it is saved.`,
+}
+
+var groupAddCmd = &cobra.Command{
+	Use:   "add <name> [host]...",
+	Short: "Add a group to the inventory",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		children, _ := cmd.Flags().GetStringSlice("children")
+		cmd.SilenceUsage = true
+
+		group := config.HostGroup{Name: args[0], Hosts: args[1:], Children: children}
+		return editInventory(func(editor *config.Editor) error {
+			return editor.AddGroup(group)
+		}, "Group %s added", group.Name)
+	},
+}
+
+var groupRmCmd = &cobra.Command{
+	Use:   "rm <name>...",
+	Short: "Remove groups from the inventory",
+	Long:  `Remove groups from the inventory. Their hosts are kept.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return editInventory(func(editor *config.Editor) error {
+			for _, name := range args {
+				if err := editor.RemoveGroup(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, "Removed %s", strings.Join(args, ", "))
+	},
+}
+
+var groupAddHostCmd = &cobra.Command{
+	Use:   "add-host <group> <host>...",
+	Short: "Add hosts to a group",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return editInventory(func(editor *config.Editor) error {
+			for _, host := range args[1:] {
+				if err := editor.AddGroupHost(args[0], host); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, "Group %s updated", args[0])
+	},
+}
+
+var groupRmHostCmd = &cobra.Command{
+	Use:   "rm-host <group> <host>...",
+	Short: "Remove hosts from a group",
+	Long:  `Remove hosts from a group. The hosts stay in the inventory.`,
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return editInventory(func(editor *config.Editor) error {
+			for _, host := range args[1:] {
+				if err := editor.RemoveGroupHost(args[0], host); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, "Group %s updated", args[0])
+	},
+}
+
+// editInventory applies edit to the inventory file and saves it, then
+// prints the formatted message
+func editInventory(edit func(*config.Editor) error, format string, args ...interface{}) error {
+	editor, err := config.Edit()
+	if err != nil {
+		return err
+	}
+	if err := edit(editor); err != nil {
+		return notFound(err)
+	}
+	if err := editor.Save(); err != nil {
+		return err
+	}
+	fmt.Printf(format+"\n", args...)
+	return nil
+}
+
+func init() {
+	groupAddCmd.Flags().StringSlice("children", nil, "Groups included in the new group")
+
+	groupCmd.AddCommand(groupAddCmd)
+	groupCmd.AddCommand(groupRmCmd)
+	groupCmd.AddCommand(groupAddHostCmd)
+	groupCmd.AddCommand(groupRmHostCmd)
+	RootCmd.AddCommand(groupCmd)
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+)
+
+var hostCmd = &cobra.Command{
+	Use:   "host",
+	Short: "Add, change and list inventory hosts",
+	Long: `Edit the hosts of the inventory file. Comments and the order of entries are kept,
+and the inventory is validated before it is saved. Hosts defined in included files
+or dynamic inventories cannot be edited.`,
+}
+
+var hostAddCmd = &cobra.Command{
+	Use:   "add <name> <address>",
+	Short: "Add a host to the inventory",
+	Long: `Add a host to the inventory, optionally adding it to existing groups with --group.
+With --check, the host is saved only if it can be reached and runs podman.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetInt("port")
+		username, _ := cmd.Flags().GetString("username")
+		privateKey, _ := cmd.Flags().GetString("private-key")
+		labels, _ := cmd.Flags().GetStringArray("label")
+		groups, _ := cmd.Flags().GetStringArray("group")
+		check, _ := cmd.Flags().GetBool("check")
+
+		host := config.Host{Name: args[0], Address: args[1], Port: port, Username: username, PrivateKey: privateKey}
+		for _, label := range labels {
+			key, value, ok := strings.Cut(label, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid label %q (expected key=value)", label)
+			}
+			if host.Labels == nil {
+				host.Labels = map[string]string{}
+			}
+			host.Labels[key] = value
+		}
+		cmd.SilenceUsage = true
+
+		editor, err := config.Edit()
+		if err != nil {
+			return err
+		}
+		if err := editor.AddHost(host); err != nil {
+			return err
+		}
+		for _, group := range groups {
+			if err := editor.AddGroupHost(group, host.Name); err != nil {
+				return notFound(err)
+			}
+		}
+
+		cfg, err := editor.Validate()
+		if err != nil {
+			return err
+		}
+		if check {
+			result := checkHostStatus(cfg.GetHostByName(host.Name))
+			if result.Status != "UP" {
+				return fmt.Errorf("host %s is not reachable, not saved: %s", host.Name, result.Error)
+			}
+		}
+		if err := editor.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Host %s added to %s\n", host.Name, editor.Path())
+		return nil
+	},
+}
+
+var hostRmCmd = &cobra.Command{
+	Use:   "rm <name>...",
+	Short: "Remove hosts from the inventory and their groups",
+	Long: `Remove hosts from the inventory and their groups.
+A host used as a jump host by other hosts, group vars or the defaults is only
+removed with --force, which also drops it from their jump hosts.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		cmd.SilenceUsage = true
+		return editInventory(func(editor *config.Editor) error {
+			for _, name := range args {
+				if err := editor.RemoveHost(name, force); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, "Removed %s", strings.Join(args, ", "))
+	},
+}
+
+var hostSetCmd = &cobra.Command{
+	Use:   "set <name> [key=value]...",
+	Short: "Change settings of a host",
+	Long: `Change settings of a host, e.g. "port=2222", "jump=[bastion]" or "labels.env=prod".
+Values are parsed as YAML. Use --unset to delete a setting. Renaming a host with
+"name=..." updates the groups and jump hosts referring to it.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unset, _ := cmd.Flags().GetStringArray("unset")
+		if len(args) == 1 && len(unset) == 0 {
+			return fmt.Errorf("nothing to change")
+		}
+		name := args[0]
+		for _, arg := range args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid setting %q (expected key=value)", arg)
+			}
+			if key == "name" {
+				name = value
+			}
+		}
+		cmd.SilenceUsage = true
+
+		return editInventory(func(editor *config.Editor) error {
+			current := args[0]
+			for _, key := range unset {
+				if err := editor.UnsetHost(current, key); err != nil {
+					return err
+				}
+			}
+			for _, arg := range args[1:] {
+				key, value, _ := strings.Cut(arg, "=")
+				if err := editor.SetHost(current, key, value); err != nil {
+					return err
+				}
+				if key == "name" {
+					current = value
+				}
+			}
+			return nil
+		}, "Host %s updated", name)
+	},
+}
+
+var hostListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List inventory hosts",
+	Long: `List the hosts of the inventory with their effective settings and groups.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
+		}
+
+		entries := make([]hostEntry, 0, len(hosts))
+		for _, h := range hosts {
+			entries = append(entries, hostEntry{
+				Name:     h.Name,
+				Address:  h.Address,
+				Port:     h.Port,
+				Username: h.Username,
+				Groups:   hostGroups(cfg, h.Name),
+				Labels:   h.Labels,
+			})
+		}
+
+		if jsonOutput(cmd) {
+			data, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Println(string(data))
+			return nil
+		}
+		displayHostTable(entries)
+		return nil
+	},
+}
+
+// hostEntry is a host as listed by host list
+type hostEntry struct {
+	Name     string            `json:"name"`
+	Address  string            `json:"address"`
+	Port     int               `json:"port,omitempty"`
+	Username string            `json:"username,omitempty"`
+	Groups   []string          `json:"groups"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// hostGroups returns the names of the groups a host belongs to
+func hostGroups(cfg *config.Config, name string) []string {
+	groups := []string{}
+	for _, g := range cfg.Groups {
+		for _, h := range cfg.GetHostsByGroup(g.Name) {
+			if h.Name == name {
+				groups = append(groups, g.Name)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+func displayHostTable(entries []hostEntry) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Address", "Port", "Username", "Groups", "Labels"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+
+	for _, e := range entries {
+		port := "22"
+		if e.Port != 0 {
+			port = strconv.Itoa(e.Port)
+		}
+		keys := make([]string, 0, len(e.Labels))
+		for k := range e.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := make([]string, len(keys))
+		for i, k := range keys {
+			labels[i] = k + "=" + e.Labels[k]
+		}
+		table.Append([]string{e.Name, e.Address, port, e.Username, strings.Join(e.Groups, ","), strings.Join(labels, ",")})
+	}
+
+	table.Render()
+}
+
+func init() {
+	hostAddCmd.Flags().IntP("port", "p", 0, "SSH port (default: 22)")
+	hostAddCmd.Flags().StringP("username", "u", "", "SSH username")
+	hostAddCmd.Flags().String("private-key", "", "Path to the SSH private key")
+	hostAddCmd.Flags().StringArray("label", nil, "Label of the host as key=value (repeatable)")
+	hostAddCmd.Flags().StringArray("group", nil, "Add the host to this existing group (repeatable)")
+	hostAddCmd.Flags().Bool("check", false, "Check that the host can be reached before saving it")
+	hostRmCmd.Flags().Bool("force", false, "Remove hosts still used as jump hosts")
+	hostSetCmd.Flags().StringArray("unset", nil, "Delete this setting, e.g. port or labels.env (repeatable)")
+	hostListCmd.Flags().StringP("hosts", "l", "", "Only list hosts matching this selector")
+	hostListCmd.Flags().Bool("json", false, "Output in JSON format")
+
+	hostCmd.AddCommand(hostAddCmd)
+	hostCmd.AddCommand(hostRmCmd)
+	hostCmd.AddCommand(hostSetCmd)
+	hostCmd.AddCommand(hostListCmd)
+	RootCmd.AddCommand(hostCmd)
+}
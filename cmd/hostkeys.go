@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+var hostkeysCmd = &cobra.Command{
+	Use:   "hostkeys",
+	Short: "Manage trusted SSH host keys",
+	Long: `Manage the SSH host keys used to verify remote hosts.
+Keys are stored in the host's known_hosts file (default: ~/.ssh/known_hosts).`,
+}
+
+var hostkeysScanCmd = &cobra.Command{
+	Use:   "scan <host/group>",
+	Short: "Fetch and trust host keys",
+	Long: `Connect to the specified host or group, fetch the presented host keys and record them in known_hosts.
+Hosts behind jump hosts are scanned through them; the keys of the jump hosts
+themselves must already be trusted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		hosts, err := hostkeysTargets(args)
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, scanHostKey)
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var hostkeysListCmd = &cobra.Command{
+	Use:   "list [host/group]",
+	Short: "List trusted host keys",
+	Long:  `Display the known_hosts entries for all hosts, or for the specified host or group.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		hosts, err := hostkeysTargets(args)
+		if err != nil {
+			return err
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Host", "Address", "Key Type", "Fingerprint", "Source"})
+		table.SetBorder(true)
+		table.SetRowLine(false)
+
+		for _, host := range hosts {
+			address := fmt.Sprintf("%s:%d", host.Address, hostPort(host))
+			if host.HostKey != "" {
+				key, _, _, _, err := cryptossh.ParseAuthorizedKey([]byte(host.HostKey))
+				if err != nil {
+					table.Append([]string{host.Name, address, "ERROR", err.Error(), "host_key"})
+					continue
+				}
+				table.Append([]string{host.Name, address, key.Type(), cryptossh.FingerprintSHA256(key), "host_key"})
+				continue
+			}
+
+			path := knownHostsPath(host)
+			entries, err := ssh.LookupKnownHost(path, host.Address, host.Port)
+			if err != nil && !os.IsNotExist(err) {
+				table.Append([]string{host.Name, address, "ERROR", err.Error(), path})
+				continue
+			}
+			if len(entries) == 0 {
+				table.Append([]string{host.Name, address, "", "not trusted", path})
+				continue
+			}
+			for _, e := range entries {
+				keyType := e.KeyType
+				if e.Marker != "" {
+					keyType = "@" + e.Marker + " " + keyType
+				}
+				table.Append([]string{host.Name, address, keyType, e.Fingerprint, fmt.Sprintf("%s:%d", path, e.Line)})
+			}
+		}
+
+		table.Render()
+		return nil
+	},
+}
+
+var hostkeysForgetCmd = &cobra.Command{
+	Use:   "forget <host/group>",
+	Short: "Remove trusted host keys",
+	Long:  `Remove the known_hosts entries of the specified host or group, e.g. after a host was reinstalled.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		hosts, err := hostkeysTargets(args)
+		if err != nil {
+			return err
+		}
+
+		// known_hosts files are local; edit them one host at a time
+		results := fanOutN(hosts, 1, false, func(host *config.Host) (string, error) {
+			path := knownHostsPath(host)
+			removed, err := ssh.ForgetHost(path, host.Address, host.Port)
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+			return fmt.Sprintf("removed %d entries from %s", removed, path), nil
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+func init() {
+	hostkeysCmd.AddCommand(hostkeysScanCmd)
+	hostkeysCmd.AddCommand(hostkeysListCmd)
+	hostkeysCmd.AddCommand(hostkeysForgetCmd)
+	RootCmd.AddCommand(hostkeysCmd)
+}
+
+// hostkeysTargets resolves the optional host/group argument, defaulting to
+// every host in the inventory
+func hostkeysTargets(args []string) ([]*config.Host, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return cfg.AllHosts(), nil
+	}
+
+	return targetHosts(cfg, args[0])
+}
+
+func scanHostKey(host *config.Host) (string, error) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	key, err := ssh.ScanHostKey(ctx, sshClientConfig(host))
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := cryptossh.FingerprintSHA256(key)
+	if host.HostKey != "" {
+		pinned, _, _, _, err := cryptossh.ParseAuthorizedKey([]byte(host.HostKey))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse host_key: %w", err)
+		}
+		if cryptossh.FingerprintSHA256(pinned) != fingerprint {
+			return "", &ssh.HostKeyMismatchError{
+				Host:        fmt.Sprintf("%s:%d", host.Address, hostPort(host)),
+				Fingerprint: fingerprint,
+				Want:        []string{cryptossh.FingerprintSHA256(pinned)},
+				Source:      "host_key",
+			}
+		}
+		return fmt.Sprintf("%s %s matches host_key", key.Type(), fingerprint), nil
+	}
+
+	path := knownHostsPath(host)
+	added, err := ssh.AddKnownHost(path, host.Address, host.Port, key)
+	if err != nil {
+		return "", err
+	}
+	if !added {
+		return fmt.Sprintf("%s %s already trusted", key.Type(), fingerprint), nil
+	}
+	return fmt.Sprintf("%s %s added to %s", key.Type(), fingerprint, path), nil
+}
+
+func knownHostsPath(host *config.Host) string {
+	if host.KnownHosts != "" {
+		return host.KnownHosts
+	}
+	return ssh.DefaultKnownHostsFile()
+}
+
+func hostPort(host *config.Host) int {
+	if host.Port == 0 {
+		return 22
+	}
+	return host.Port
+}
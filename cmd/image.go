@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Manage images across hosts",
+}
+
+var imageLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List images on all hosts",
+	Long: `List the images of all hosts in one table with a Host column.
+With --summary, every image is listed once with the number of hosts it is present on.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		summary, _ := cmd.Flags().GetBool("summary")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
+		}
+
+		hostResults := fanOut(hosts, func(host *config.Host) (*podman.ImageListResult, error) {
+			return listImagesOnHost(host), nil
+		})
+		results := make([]*podman.ImageListResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
+
+		switch {
+		case summary && jsonOutput(cmd):
+			printJSON(imagePresences(results))
+		case summary:
+			displayImagePresenceTable(imagePresences(results), results)
+		case jsonOutput(cmd):
+			printJSON(results)
+		default:
+			displayImageTable(results)
+		}
+		return summarize(listStatus(hostResults, func(r *podman.ImageListResult) string { return r.Error }))
+	},
+}
+
+var imagePullCmd = &cobra.Command{
+	Use:   "pull <host/group> <image>...",
+	Short: "Pull images on hosts",
+	Long: `Pull images on every selected host in parallel. Progress is printed with the host name
+in front of every line; use --quiet to only print the pulled image IDs.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		var mu sync.Mutex
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			var progress io.Writer
+			if !quiet {
+				w := newLinePrefixWriter(&mu, os.Stderr, fmt.Sprintf("[%s] ", host.Name))
+				defer w.Flush()
+				progress = w
+			}
+			return pullImagesOnHost(host, args[1:], progress)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var imageRmCmd = &cobra.Command{
+	Use:   "rm <host/group> <image>...",
+	Short: "Remove images from hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return removeImagesOnHost(host, args[1:], force)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var imagePruneCmd = &cobra.Command{
+	Use:   "prune <host/group>",
+	Short: "Remove unused images from hosts",
+	Long: `Remove dangling images from every selected host, or with --all every image not used by
+a container. Filters limit the images removed, e.g. --filter until=240h.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		filters, _ := cmd.Flags().GetStringArray("filter")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) ([]string, error) {
+			return pruneImagesOnHost(host, all, filters)
+		})
+
+		if jsonOutput(cmd) {
+			entries := make([]pruneEntry, 0, len(results))
+			for _, r := range results {
+				entry := pruneEntry{Host: r.Host.Name, Removed: r.Value}
+				if entry.Removed == nil {
+					entry.Removed = []string{}
+				}
+				if r.Err != nil {
+					entry.Error = r.Err.Error()
+				}
+				entries = append(entries, entry)
+			}
+			printJSON(entries)
+		} else {
+			printHostResults(pruneSummaries(results))
+		}
+		return summarize(results)
+	},
+}
+
+// pruneEntry is the JSON output of prune for one host
+type pruneEntry struct {
+	Host    string   `json:"host"`
+	Removed []string `json:"removed"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// pruneSummaries describes the objects removed from every host, e.g.
+// "removed 2: 0123456789ab, ba9876543210"
+func pruneSummaries(results []hostResult[[]string]) []hostResult[string] {
+	summaries := make([]hostResult[string], len(results))
+	for i, r := range results {
+		summaries[i] = hostResult[string]{Host: r.Host, Err: r.Err}
+		if r.Err != nil {
+			continue
+		}
+		if len(r.Value) == 0 {
+			summaries[i].Value = "nothing to remove"
+			continue
+		}
+		ids := make([]string, len(r.Value))
+		for j, id := range r.Value {
+			ids[j] = shortID(id)
+		}
+		summaries[i].Value = fmt.Sprintf("removed %d: %s", len(ids), strings.Join(ids, ", "))
+	}
+	return summaries
+}
+
+func init() {
+	imageLsCmd.Flags().Bool("json", false, "Output in JSON format")
+	imageLsCmd.Flags().StringP("hosts", "l", "", "Only list images on hosts matching this selector")
+	imageLsCmd.Flags().Bool("summary", false, "List every image once with the hosts it is present on")
+	imagePullCmd.Flags().BoolP("quiet", "q", false, "Do not print pull progress")
+	imageRmCmd.Flags().BoolP("force", "f", false, "Also remove containers using the images")
+	imagePruneCmd.Flags().BoolP("all", "a", false, "Remove all images not used by a container, not only dangling ones")
+	imagePruneCmd.Flags().StringArray("filter", nil, "Only remove images matching this filter, e.g. until=240h (repeatable)")
+	imagePruneCmd.Flags().Bool("json", false, "Output in JSON format")
+
+	imageCmd.AddCommand(imageLsCmd)
+	imageCmd.AddCommand(imagePullCmd)
+	imageCmd.AddCommand(imageRmCmd)
+	imageCmd.AddCommand(imagePruneCmd)
+	RootCmd.AddCommand(imageCmd)
+}
+
+func listImagesOnHost(host *config.Host) *podman.ImageListResult {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	result := &podman.ImageListResult{Hostname: host.Name}
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if result.Images, err = backend.ListImages(ctx); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// pullImagesOnHost pulls images in order and returns their short IDs
+func pullImagesOnHost(host *config.Host, images []string, progress io.Writer) (string, error) {
+	ctx, cancel := commandContext(30 * time.Minute)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+
+	loggedIn := map[string]bool{}
+	ids := make([]string, 0, len(images))
+	for _, image := range images {
+		if registry := podman.Registry(image); !loggedIn[registry] {
+			if err := registryLogin(ctx, host, image); err != nil {
+				return "", err
+			}
+			loggedIn[registry] = true
+		}
+		id, err := backend.PullImage(ctx, image, progress)
+		if err != nil {
+			return "", fmt.Errorf("failed to pull %s: %w", image, err)
+		}
+		ids = append(ids, shortID(id))
+	}
+	return strings.Join(ids, " "), nil
+}
+
+func removeImagesOnHost(host *config.Host, images []string, force bool) (string, error) {
+	ctx, cancel := commandContext(60 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, image := range images {
+		if err := backend.RemoveImage(ctx, image, force); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", image, err)
+		}
+	}
+	return strings.Join(images, " "), nil
+}
+
+func pruneImagesOnHost(host *config.Host, all bool, filters []string) ([]string, error) {
+	ctx, cancel := commandContext(10 * time.Minute)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return backend.PruneImages(ctx, all, filters)
+}
+
+// imageNames returns the "repository:tag" names of an image, or "<none>"
+// for a dangling image
+func imageNames(image podman.Image) []string {
+	if len(image.RepoTags) == 0 {
+		return []string{"<none>"}
+	}
+	return image.RepoTags
+}
+
+func displayImageTable(results []*podman.ImageListResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Host", "Repository", "Tag", "Image ID", "Created", "Size"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+
+	for _, result := range results {
+		if result.Error != "" {
+			table.Append([]string{result.Hostname, "ERROR", result.Error, "", "", ""})
+			continue
+		}
+
+		for _, image := range result.Images {
+			for _, name := range imageNames(image) {
+				repository, tag := podman.SplitReference(name)
+				if tag == "" {
+					tag = "<none>"
+				}
+				table.Append([]string{
+					result.Hostname,
+					repository,
+					tag,
+					image.ShortID(),
+					formatTime(image.Created),
+					formatSize(image.Size),
+				})
+			}
+		}
+	}
+
+	table.Render()
+}
+
+// imagePresence tells which hosts have an image
+type imagePresence struct {
+	Image   string   `json:"image"`
+	ID      string   `json:"id"`
+	Hosts   []string `json:"hosts"`
+	Missing []string `json:"missing"`
+}
+
+// imagePresences groups the images of every host by name and ID. A name
+// with different IDs on different hosts is listed once per ID. Hosts whose
+// images could not be listed are left out.
+func imagePresences(results []*podman.ImageListResult) []imagePresence {
+	var listed []string
+	byKey := map[string]*imagePresence{}
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		listed = append(listed, result.Hostname)
+		for _, image := range result.Images {
+			for _, name := range imageNames(image) {
+				key := name + "\x00" + image.ID
+				p, ok := byKey[key]
+				if !ok {
+					p = &imagePresence{Image: name, ID: image.ShortID()}
+					byKey[key] = p
+				}
+				if len(p.Hosts) == 0 || p.Hosts[len(p.Hosts)-1] != result.Hostname {
+					p.Hosts = append(p.Hosts, result.Hostname)
+				}
+			}
+		}
+	}
+
+	presences := make([]imagePresence, 0, len(byKey))
+	for _, p := range byKey {
+		p.Missing = []string{}
+		for _, host := range listed {
+			if !slices.Contains(p.Hosts, host) {
+				p.Missing = append(p.Missing, host)
+			}
+		}
+		presences = append(presences, *p)
+	}
+	sort.Slice(presences, func(i, j int) bool {
+		if presences[i].Image != presences[j].Image {
+			return presences[i].Image < presences[j].Image
+		}
+		return presences[i].ID < presences[j].ID
+	})
+	return presences
+}
+
+func displayImagePresenceTable(presences []imagePresence, results []*podman.ImageListResult) {
+	listed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "Error on %s: %s\n", result.Hostname, result.Error)
+			continue
+		}
+		listed++
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Image", "Image ID", "Present", "Missing On"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+
+	for _, p := range presences {
+		table.Append([]string{
+			p.Image,
+			p.ID,
+			fmt.Sprintf("%d/%d hosts", len(p.Hosts), listed),
+			strings.Join(p.Missing, ", "),
+		})
+	}
+	table.Render()
+}
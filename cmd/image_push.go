@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+var imagePushToCmd = &cobra.Command{
+	Use:   "push-to <image> <src-host|local-tar> <host/group>",
+	Short: "Copy an image to hosts without a registry",
+	Long: `Stream an image from a host (podman save) or from a local image archive through this
+machine into podman load on every selected host, without temporary files. All destinations
+receive the same stream at once, regardless of --parallel, so the slowest host sets the pace
+for all of them.
+
+Hosts that already have the image with the same ID are skipped unless --force is given.
+With --compress, the stream is compressed with zstd, which must then be installed on the
+hosts along with bash (and zstd locally for a local archive). Archives ending in .zst are
+sent as they are, and their image ID is read with a local zstd.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		image, source, target := args[0], args[1], args[2]
+		compress, _ := cmd.Flags().GetBool("compress")
+		force, _ := cmd.Flags().GetBool("force")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, target)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := commandContext(60 * time.Minute)
+		defer cancel()
+
+		src, err := openImageSource(ctx, cfg, source, image, compress)
+		if err != nil {
+			return err
+		}
+
+		// The source host already has the image
+		var dests []*config.Host
+		for _, host := range hosts {
+			if src.host == nil || host.Name != src.host.Name {
+				dests = append(dests, host)
+			}
+		}
+
+		current := map[string]bool{}
+		if !force && src.id != "" {
+			for _, r := range fanOut(dests, func(host *config.Host) (bool, error) {
+				backend, err := podmanBackend(ctx, host)
+				if err != nil {
+					return false, err
+				}
+				return hasImage(ctx, backend, image, src.id)
+			}) {
+				current[r.Host.Name] = r.Err == nil && r.Value
+			}
+		}
+		var pending []*config.Host
+		for _, host := range dests {
+			if !current[host.Name] {
+				pending = append(pending, host)
+			}
+		}
+
+		loaded, err := broadcast(pending, src.copy, func(host *config.Host, r io.Reader) (string, error) {
+			client, err := connect(ctx, host)
+			if err != nil {
+				return "", fmt.Errorf("failed to connect to host: %w", err)
+			}
+			return podman.LoadImage(ctx, client, r, src.compressed)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read image from %s: %w", source, err)
+		}
+
+		results := make([]hostResult[string], 0, len(dests))
+		for _, host := range dests {
+			if current[host.Name] {
+				results = append(results, hostResult[string]{Host: host, Value: "already has image " + shortID(src.id)})
+			}
+		}
+		results = append(results, loaded...)
+		sortHostResults(results)
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+func init() {
+	imagePushToCmd.Flags().Bool("compress", false, "Compress the stream with zstd")
+	imagePushToCmd.Flags().Bool("force", false, "Also send the image to hosts that already have it")
+	imageCmd.AddCommand(imagePushToCmd)
+}
+
+// imageSource is where push-to reads an image archive from
+type imageSource struct {
+	// host is the inventory host the image is saved on, or nil for a
+	// local archive
+	host *config.Host
+	// id is the image ID, or "" if it is not known
+	id string
+	// compressed tells whether the stream is compressed with zstd
+	compressed bool
+	// copy writes the archive to w
+	copy func(w io.Writer) error
+}
+
+// openImageSource resolves source to an inventory host, or else to a local
+// archive, and finds the ID of the image it provides
+func openImageSource(ctx context.Context, cfg *config.Config, source, image string, compress bool) (*imageSource, error) {
+	if host := cfg.GetHostByName(source); host != nil {
+		backend, err := podmanBackend(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		client, err := connect(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to host: %w", err)
+		}
+		return hostImageSource(ctx, host, backend, client, image, compress)
+	}
+
+	if _, err := os.Stat(source); err != nil {
+		return nil, &exitError{code: exitNotFound, err: fmt.Errorf("'%s' is neither a host in the configuration nor a file", source)}
+	}
+
+	src := &imageSource{compressed: compress || strings.HasSuffix(source, ".zst")}
+	if src.id, _ = archiveImageID(ctx, source); src.id == "" {
+		fmt.Fprintf(os.Stderr, "Warning: cannot tell the image ID of %s; sending it to every host\n", source)
+	}
+
+	switch {
+	case compress && !strings.HasSuffix(source, ".zst"):
+		src.copy = func(w io.Writer) error {
+			zstd := exec.CommandContext(ctx, "zstd", "-c", "-q", source)
+			zstd.Stdout = w
+			zstd.Stderr = os.Stderr
+			return zstd.Run()
+		}
+	default:
+		src.copy = func(w io.Writer) error {
+			f, err := os.Open(source)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		}
+	}
+	return src, nil
+}
+
+// hostImageSource saves image on an inventory host, after finding its ID
+// through backend
+func hostImageSource(ctx context.Context, host *config.Host, backend podman.Backend, client ssh.Client, image string, compress bool) (*imageSource, error) {
+	inspect, err := backend.InspectImage(ctx, image)
+	if err != nil {
+		if podman.IsNotFound(err) {
+			return nil, &exitError{code: exitNotFound, err: fmt.Errorf("image %s not found on %s", image, host.Name)}
+		}
+		return nil, err
+	}
+	return &imageSource{
+		host:       host,
+		id:         inspect.ID,
+		compressed: compress,
+		copy: func(w io.Writer) error {
+			return podman.SaveImage(ctx, client, image, compress, w)
+		},
+	}, nil
+}
+
+// archiveImageID returns the ID of the image in a local docker-archive,
+// decompressed with zstd if its name ends in .zst
+func archiveImageID(ctx context.Context, file string) (string, error) {
+	if !strings.HasSuffix(file, ".zst") {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return podman.ArchiveImageID(f)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	zstd := exec.CommandContext(ctx, "zstd", "-d", "-c", "-q", file)
+	stdout, err := zstd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := zstd.Start(); err != nil {
+		return "", err
+	}
+	id, err := podman.ArchiveImageID(stdout)
+	// The rest of the archive is not needed
+	cancel()
+	zstd.Wait()
+	return id, err
+}
+
+// hasImage reports whether ref names the image with the given ID on the
+// host of backend
+func hasImage(ctx context.Context, backend podman.Backend, ref, id string) (bool, error) {
+	image, err := backend.InspectImage(ctx, ref)
+	if err != nil {
+		if podman.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.TrimPrefix(image.ID, "sha256:") == strings.TrimPrefix(id, "sha256:"), nil
+}
+
+// errNoReceivers is returned to the source of a broadcast when every
+// destination has failed
+var errNoReceivers = errors.New("no destination is receiving")
+
+// broadcast runs load on every host at once, each reading the data written
+// by src. A host whose load fails stops receiving data without holding up
+// the others. It returns the results of the hosts and the error of src.
+func broadcast(hosts []*config.Host, src func(w io.Writer) error, load func(host *config.Host, r io.Reader) (string, error)) ([]hostResult[string], error) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	readers := map[string]*io.PipeReader{}
+	tee := &teeWriter{}
+	for _, host := range hosts {
+		r, w := io.Pipe()
+		readers[host.Name] = r
+		tee.writers = append(tee.writers, w)
+	}
+
+	srcDone := make(chan error, 1)
+	go func() {
+		err := src(tee)
+		for _, w := range tee.writers {
+			if w != nil {
+				w.CloseWithError(err)
+			}
+		}
+		srcDone <- err
+	}()
+
+	results := fanOutN(hosts, 0, false, func(host *config.Host) (string, error) {
+		r := readers[host.Name]
+		output, err := load(host, r)
+		if err == nil && output == "" {
+			output = "loaded"
+		}
+		// Unblock the source if the host stopped reading early
+		r.CloseWithError(errNoReceivers)
+		return output, err
+	})
+
+	err := <-srcDone
+	if errors.Is(err, errNoReceivers) {
+		// Every host failed and reports its own error
+		err = nil
+	}
+	return results, err
+}
+
+// teeWriter writes to every destination still receiving, to all of them at
+// once so that each write takes as long as the slowest destination rather
+// than the sum of them. It fails only when no destination is left.
+type teeWriter struct {
+	writers []*io.PipeWriter
+}
+
+func (t *teeWriter) Write(data []byte) (int, error) {
+	var wg sync.WaitGroup
+	failed := make([]bool, len(t.writers))
+	for i, w := range t.writers {
+		if w == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := w.Write(data)
+			failed[i] = err != nil
+		}()
+	}
+	wg.Wait()
+
+	live := 0
+	for i, w := range t.writers {
+		if w == nil {
+			continue
+		}
+		if failed[i] {
+			t.writers[i] = nil
+			continue
+		}
+		live++
+	}
+	if live == 0 {
+		return 0, errNoReceivers
+	}
+	return len(data), nil
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/podman-swarm/cmd/internal/test"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+// TestImagePresences verifies grouping of images by name and ID across hosts
+func TestImagePresences(t *testing.T) {
+	results := []*podman.ImageListResult{
+		{Hostname: "host1", Images: []podman.Image{
+			{ID: "aaaa", RepoTags: []string{"nginx:1.25", "nginx:latest"}},
+			{ID: "cccc"},
+		}},
+		{Hostname: "host2", Images: []podman.Image{
+			{ID: "bbbb", RepoTags: []string{"nginx:latest"}},
+		}},
+		{Hostname: "host3", Error: "connection refused"},
+	}
+
+	got := imagePresences(results)
+	want := []imagePresence{
+		{Image: "<none>", ID: "cccc", Hosts: []string{"host1"}, Missing: []string{"host2"}},
+		{Image: "nginx:1.25", ID: "aaaa", Hosts: []string{"host1"}, Missing: []string{"host2"}},
+		{Image: "nginx:latest", ID: "aaaa", Hosts: []string{"host1"}, Missing: []string{"host2"}},
+		{Image: "nginx:latest", ID: "bbbb", Hosts: []string{"host2"}, Missing: []string{"host1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got: %+v", want, got)
+	}
+}
+
+// TestFormatSize verifies decimal size units
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1 kB"},
+		{187_000_000, "187 MB"},
+		{1_234_567_890, "1.23 GB"},
+	}
+	for _, tc := range tests {
+		if got := formatSize(tc.size); got != tc.want {
+			t.Errorf("formatSize(%d) = %s, want: %s", tc.size, got, tc.want)
+		}
+	}
+}
+
+// TestBroadcast verifies that every host receives the whole stream and that a
+// failing host does not hold up the others
+func TestBroadcast(t *testing.T) {
+	hosts := []*config.Host{{Name: "host2"}, {Name: "host1"}, {Name: "broken"}}
+	data := strings.Repeat("layer data ", 100000)
+
+	results, err := broadcast(hosts, func(w io.Writer) error {
+		_, err := io.Copy(w, strings.NewReader(data))
+		return err
+	}, func(host *config.Host, r io.Reader) (string, error) {
+		if host.Name == "broken" {
+			return "", errors.New("podman load failed")
+		}
+		received, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d bytes", len(received)), nil
+	})
+	if err != nil {
+		t.Fatalf("broadcast should succeed, got: %v", err)
+	}
+
+	want := fmt.Sprintf("%d bytes", len(data))
+	if results[0].Host.Name != "broken" || results[0].Err == nil {
+		t.Errorf("expected broken to fail, got: %+v", results[0])
+	}
+	for _, r := range results[1:] {
+		if r.Err != nil || r.Value != want {
+			t.Errorf("%s: expected %s, got: %q, %v", r.Host.Name, want, r.Value, r.Err)
+		}
+	}
+}
+
+// TestBroadcast_SourceError verifies that a failing source is reported and
+// interrupts the hosts
+func TestBroadcast_SourceError(t *testing.T) {
+	hosts := []*config.Host{{Name: "host1"}}
+	_, err := broadcast(hosts, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("image not known")
+	}, func(host *config.Host, r io.Reader) (string, error) {
+		_, err := io.ReadAll(r)
+		return "", err
+	})
+	if err == nil || err.Error() != "image not known" {
+		t.Errorf("expected the source error, got: %v", err)
+	}
+}
+
+// TestBroadcast_Concurrent verifies that each write reaches the hosts at
+// once rather than one after the other
+func TestBroadcast_Concurrent(t *testing.T) {
+	hosts := []*config.Host{{Name: "host1"}, {Name: "host2"}}
+	host2Received := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		broadcast(hosts, func(w io.Writer) error {
+			_, err := w.Write([]byte("layer"))
+			return err
+		}, func(host *config.Host, r io.Reader) (string, error) {
+			// host1 only reads once host2 has its data, which a serial
+			// write to host1 first would never let happen
+			if host.Name == "host1" {
+				<-host2Received
+			}
+			buf := make([]byte, 5)
+			_, err := io.ReadFull(r, buf)
+			if host.Name == "host2" {
+				close(host2Received)
+			}
+			return "", err
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast should write to the hosts at once")
+	}
+}
+
+// TestArchiveImageID_Zstd verifies that the image ID of a compressed local
+// archive is read, so that hosts having the image can be skipped
+func TestArchiveImageID_Zstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not installed")
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	manifest := `[{"Config": "4f3e2d1c.json", "RepoTags": ["app:latest"]}]`
+	tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest))})
+	tw.Write([]byte(manifest))
+	tw.Close()
+
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	if err := os.WriteFile(archive, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("zstd", "-q", "--rm", archive).CombinedOutput(); err != nil {
+		t.Fatalf("zstd failed: %v: %s", err, out)
+	}
+
+	id, err := archiveImageID(context.Background(), archive+".zst")
+	if err != nil || id != "4f3e2d1c" {
+		t.Errorf("expected 4f3e2d1c, got: %q, %v", id, err)
+	}
+}
+
+// TestRegistryCredentials verifies resolution of username:password
+// credentials
+func TestRegistryCredentials(t *testing.T) {
+	t.Setenv("TEST_REGISTRY", "deploy:s3cr:et")
+	host := &config.Host{Name: "web1", Registries: map[string]config.Secret{
+		"quay.io":     "env://TEST_REGISTRY",
+		"example.com": "token-only",
+	}}
+
+	username, password, err := registryCredentials(host, "quay.io")
+	if err != nil || username != "deploy" || password != "s3cr:et" {
+		t.Errorf("unexpected credentials: %q, %q, %v", username, password, err)
+	}
+	if username, _, err := registryCredentials(host, "docker.io"); err != nil || username != "" {
+		t.Errorf("expected no credentials, got: %q, %v", username, err)
+	}
+	if _, _, err := registryCredentials(host, "example.com"); err == nil {
+		t.Error("expected an error without a username")
+	}
+}
+
+// newTestAPIBackend returns an API backend whose socket is forwarded by a
+// mock SSH client to handler
+func newTestAPIBackend(t *testing.T, client *test.MockSSHClient, handler http.Handler) podman.Backend {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client.DialSocketFunc = func(ctx context.Context, path string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+	backend, err := podman.NewBackend(context.Background(), podman.BackendAPI, client, "/run/podman/podman.sock")
+	if err != nil {
+		t.Fatalf("NewBackend should succeed, got: %v", err)
+	}
+	return backend
+}
+
+// TestPushTo_APIBackend verifies that push-to finds a qualified image on API
+// source and destination hosts
+func TestPushTo_APIBackend(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Libpod-API-Version", "5.0.0")
+	})
+	mux.HandleFunc("/v5.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/v5.0.0/libpod/images/docker.io%2Flibrary%2Fnginx:latest/json" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cause": "image not known", "message": "image not known", "response": 404}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "abcd", "RepoTags": ["docker.io/library/nginx:latest"]}`))
+	})
+
+	var saved string
+	client := &test.MockSSHClient{StreamFunc: func(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+		saved = cmd
+		return nil
+	}}
+	backend := newTestAPIBackend(t, client, mux)
+	host := &config.Host{Name: "build1", PodmanBackend: podman.BackendAPI}
+	ctx := context.Background()
+
+	src, err := hostImageSource(ctx, host, backend, client, "docker.io/library/nginx:latest", false)
+	if err != nil || src.id != "abcd" {
+		t.Fatalf("expected image abcd, got: %+v, %v", src, err)
+	}
+	if err := src.copy(io.Discard); err != nil || !strings.Contains(saved, "docker.io/library/nginx:latest") {
+		t.Errorf("unexpected save: %q, %v", saved, err)
+	}
+
+	var exitErr *exitError
+	if _, err := hostImageSource(ctx, host, backend, client, "quay.io/example/missing", false); !errors.As(err, &exitErr) || exitErr.code != exitNotFound {
+		t.Errorf("expected a not found exit error, got: %v", err)
+	}
+
+	if present, err := hasImage(ctx, backend, "docker.io/library/nginx:latest", "sha256:abcd"); err != nil || !present {
+		t.Errorf("destination should have the image, got: %v, %v", present, err)
+	}
+	if present, err := hasImage(ctx, backend, "docker.io/library/nginx:latest", "ef01"); err != nil || present {
+		t.Errorf("destination has another image, got: %v, %v", present, err)
+	}
+	if present, err := hasImage(ctx, backend, "quay.io/example/missing", "abcd"); err != nil || present {
+		t.Errorf("destination does not have the image, got: %v, %v", present, err)
+	}
+}
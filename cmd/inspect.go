@@ -1,15 +1,12 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
-	"github.com/ytnobody/podman-swarm/pkg/podman"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
 )
 
 var inspectCmd = &cobra.Command{
@@ -28,24 +25,18 @@ var inspectCmd = &cobra.Command{
 
 		host := cfg.GetHostByName(hostName)
 		if host == nil {
-			return fmt.Errorf("host '%s' not found in configuration", hostName)
+			return &exitError{code: exitNotFound, err: fmt.Errorf("host '%s' not found in configuration", hostName)}
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := commandContext(10 * time.Second)
 		defer cancel()
 
-		client, err := ssh.NewClient(ssh.ClientConfig{
-			Host:       host.Address,
-			Port:       host.Port,
-			Username:   host.Username,
-			PrivateKey: host.PrivateKey,
-		})
+		backend, err := podmanBackend(ctx, host)
 		if err != nil {
-			return fmt.Errorf("failed to connect to host: %w", err)
+			return err
 		}
-		defer client.Close()
 
-		result, err := podman.InspectContainer(ctx, hostName, client, containerID)
+		result, err := backend.InspectContainer(ctx, containerID)
 		if err != nil {
 			return err
 		}
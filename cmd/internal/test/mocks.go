@@ -2,14 +2,23 @@ package test
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net"
+
 	"github.com/ytnobody/podman-swarm/pkg/config"
 	"github.com/ytnobody/podman-swarm/pkg/ssh"
 )
 
 // MockSSHClient is a mock implementation of ssh.Client
 type MockSSHClient struct {
-	ExecuteFunc func(ctx context.Context, cmd string) (string, error)
-	CloseFunc   func() error
+	ExecuteFunc     func(ctx context.Context, cmd string) (string, error)
+	StreamFunc      func(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error
+	InteractiveFunc func(ctx context.Context, cmd string, opts ssh.InteractiveOptions) error
+	CloseFunc       func() error
+	// DialSocketFunc makes the mock an ssh.SocketDialer, e.g. for the API
+	// backend
+	DialSocketFunc func(ctx context.Context, path string) (net.Conn, error)
 }
 
 func (m *MockSSHClient) Execute(ctx context.Context, cmd string) (string, error) {
@@ -19,6 +28,35 @@ func (m *MockSSHClient) Execute(ctx context.Context, cmd string) (string, error)
 	return "", nil
 }
 
+// Stream calls StreamFunc if set; otherwise it writes the result of
+// ExecuteFunc to stdout
+func (m *MockSSHClient) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if m.StreamFunc != nil {
+		return m.StreamFunc(ctx, cmd, stdin, stdout, stderr)
+	}
+	output, err := m.Execute(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, output)
+	return err
+}
+
+// Interactive calls InteractiveFunc if set; otherwise it behaves like Stream
+func (m *MockSSHClient) Interactive(ctx context.Context, cmd string, opts ssh.InteractiveOptions) error {
+	if m.InteractiveFunc != nil {
+		return m.InteractiveFunc(ctx, cmd, opts)
+	}
+	return m.Stream(ctx, cmd, opts.Stdin, opts.Stdout, opts.Stderr)
+}
+
+func (m *MockSSHClient) DialSocket(ctx context.Context, path string) (net.Conn, error) {
+	if m.DialSocketFunc != nil {
+		return m.DialSocketFunc(ctx, path)
+	}
+	return nil, errors.New("socket forwarding is not supported")
+}
+
 func (m *MockSSHClient) Close() error {
 	if m.CloseFunc != nil {
 		return m.CloseFunc()
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Import inventories from other tools",
+}
+
+var inventoryImportCmd = &cobra.Command{
+	Use:   "import --from ansible <file>",
+	Short: "Convert an inventory into podman-swarm's format",
+	Long: `Convert an Ansible inventory (INI or YAML) into podman-swarm's hosts.yaml format.
+Hosts, groups, children and connection variables are converted; other variables become labels.
+The result is written to stdout, or to a new file with --output.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		output, _ := cmd.Flags().GetString("output")
+
+		if from != "ansible" {
+			return fmt.Errorf("unsupported inventory format %q (supported: ansible)", from)
+		}
+		cmd.SilenceUsage = true
+
+		cfg, warnings, err := config.ImportAnsible(args[0])
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, "Warning:", w)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			// Never overwrite an existing inventory
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg); err != nil {
+			return err
+		}
+		return enc.Close()
+	},
+}
+
+func init() {
+	inventoryImportCmd.Flags().String("from", "", "Format of the inventory to import (ansible)")
+	inventoryImportCmd.Flags().StringP("output", "o", "", "Write the result to a new file instead of stdout")
+	inventoryImportCmd.MarkFlagRequired("from")
+	inventoryCmd.AddCommand(inventoryImportCmd)
+	RootCmd.AddCommand(inventoryCmd)
+}
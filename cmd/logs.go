@@ -1,68 +1,136 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
 )
 
 var logsCmd = &cobra.Command{
 	Use:   "logs <host/group> <cid/name>",
 	Short: "Display container logs",
-	Long:  `Fetch and display logs from a container on specified host.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Fetch and display logs from a container on specified host.
+With --follow, logs of every host are streamed concurrently and each line is prefixed with the host name.`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostOrGroup := args[0]
 		container := args[1]
+		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetInt("tail")
+
+		cmd.SilenceUsage = true
 
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		hosts := cfg.GetHostOrGroup(hostOrGroup)
-		if hosts == nil || len(hosts) == 0 {
-			return fmt.Errorf("host or group '%s' not found", hostOrGroup)
+		hosts, err := targetHosts(cfg, hostOrGroup)
+		if err != nil {
+			return err
+		}
+
+		cmdStr := logsCommand(container, follow, tail)
+		if follow {
+			return summarize(followContainerLogs(hosts, cmdStr))
 		}
 
-		for _, host := range hosts {
-			if err := getContainerLogs(host, container); err != nil {
-				fmt.Printf("Error on %s: %v\n", host.Name, err)
+		results := fanOut(hosts, func(host *config.Host) (logOutput, error) {
+			return getContainerLogs(host, cmdStr)
+		})
+		for _, r := range results {
+			fmt.Printf("[%s]\n", r.Host.Name)
+			os.Stdout.Write(r.Value.Stdout.Bytes())
+			os.Stderr.Write(r.Value.Stderr.Bytes())
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error on %s: %v\n", r.Host.Name, r.Err)
 			}
 		}
-		return nil
+		return summarize(results)
 	},
 }
 
-func getContainerLogs(host *config.Host, container string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func logsCommand(container string, follow bool, tail int) string {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	return podman.Command(append(args, container)...)
+}
+
+// logOutput is the log output of one host, buffered so that hosts are
+// printed in order
+type logOutput struct {
+	Stdout bytes.Buffer
+	Stderr bytes.Buffer
+}
+
+func getContainerLogs(host *config.Host, cmdStr string) (logOutput, error) {
+	var out logOutput
+
+	ctx, cancel := commandContext(60 * time.Second)
 	defer cancel()
 
-	client, err := ssh.NewClient(ssh.ClientConfig{
-		Host:       host.Address,
-		Port:       host.Port,
-		Username:   host.Username,
-		PrivateKey: host.PrivateKey,
-	})
+	client, err := connect(ctx, host)
 	if err != nil {
-		return fmt.Errorf("failed to connect to host: %w", err)
+		return out, fmt.Errorf("failed to connect to host: %w", err)
 	}
 	defer client.Close()
 
-	cmdStr := fmt.Sprintf("podman logs %s", container)
-	output, err := client.Execute(ctx, cmdStr)
-	if err != nil {
-		return err
+	err = client.Stream(ctx, cmdStr, nil, &out.Stdout, &out.Stderr)
+	return out, err
+}
+
+// followContainerLogs streams logs from every host until interrupted. All
+// hosts are followed at once, regardless of --parallel.
+func followContainerLogs(hosts []*config.Host, cmdStr string) []hostResult[struct{}] {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
 	}
 
-	fmt.Printf("[%s]\n%s\n", host.Name, output)
-	return nil
+	var mu sync.Mutex
+	return fanOutN(hosts, 0, false, func(h *config.Host) (struct{}, error) {
+		stdout := newLinePrefixWriter(&mu, os.Stdout, fmt.Sprintf("[%s] ", h.Name))
+		stderr := newLinePrefixWriter(&mu, os.Stderr, fmt.Sprintf("[%s] ", h.Name))
+		defer stdout.Flush()
+		defer stderr.Flush()
+
+		client, err := connect(ctx, h)
+		if err != nil {
+			err = fmt.Errorf("failed to connect to host: %w", err)
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return struct{}{}, err
+		}
+		defer client.Close()
+
+		err = client.Stream(ctx, cmdStr, nil, stdout, stderr)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return struct{}{}, err
+		}
+		return struct{}{}, nil
+	})
 }
 
 func init() {
+	logsCmd.Flags().BoolP("follow", "f", false, "Follow log output")
+	logsCmd.Flags().Int("tail", -1, "Output the specified number of lines at the end of logs (default: all)")
 	RootCmd.AddCommand(logsCmd)
 }
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manage networks across hosts",
+}
+
+var networkLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List networks on all hosts",
+	Long: `List the networks of all hosts in one table with a Host column.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
+		}
+
+		hostResults := fanOut(hosts, func(host *config.Host) (*podman.NetworkListResult, error) {
+			return listNetworksOnHost(host), nil
+		})
+		results := make([]*podman.NetworkListResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
+
+		if jsonOutput(cmd) {
+			printJSON(results)
+		} else {
+			displayNetworkTable(results)
+		}
+		return summarize(listStatus(hostResults, func(r *podman.NetworkListResult) string { return r.Error }))
+	},
+}
+
+var networkCreateCmd = &cobra.Command{
+	Use:   "create <host/group> <name>",
+	Short: "Create a network on hosts",
+	Long: `Create a network with the same settings on every selected host. Without --subnet, each
+host allocates a free subnet of its own.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		driver, _ := cmd.Flags().GetString("driver")
+		subnet, _ := cmd.Flags().GetString("subnet")
+		gateway, _ := cmd.Flags().GetString("gateway")
+		internal, _ := cmd.Flags().GetBool("internal")
+		labels, _ := cmd.Flags().GetStringArray("label")
+
+		create := podman.NetworkCreateOptions{Name: args[1], Driver: driver, Subnet: subnet, Gateway: gateway, Internal: internal}
+		var err error
+		if create.Labels, err = keyValues("label", labels); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return createNetworkOnHost(host, create)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var networkRmCmd = &cobra.Command{
+	Use:   "rm <host/group> <name>...",
+	Short: "Remove networks from hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return removeNetworksOnHost(host, args[1:], force)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var networkInspectCmd = &cobra.Command{
+	Use:   "inspect <host/group> <name>",
+	Short: "Display network details on hosts",
+	Long:  `Inspect a network on every selected host and display the results in JSON format, one entry per host.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (*podman.Network, error) {
+			return inspectNetworkOnHost(host, args[1])
+		})
+		printJSON(inspectEntries(results))
+		return summarize(results)
+	},
+}
+
+func init() {
+	networkLsCmd.Flags().Bool("json", false, "Output in JSON format")
+	networkLsCmd.Flags().StringP("hosts", "l", "", "Only list networks on hosts matching this selector")
+	networkCreateCmd.Flags().String("driver", "", "Network driver: bridge, macvlan or ipvlan (default bridge)")
+	networkCreateCmd.Flags().String("subnet", "", "Subnet in CIDR notation, e.g. 10.89.10.0/24")
+	networkCreateCmd.Flags().String("gateway", "", "Gateway of the subnet")
+	networkCreateCmd.Flags().Bool("internal", false, "Restrict external access from the network")
+	networkCreateCmd.Flags().StringArray("label", nil, "Set a label as key=value (repeatable)")
+	networkRmCmd.Flags().BoolP("force", "f", false, "Also remove containers and pods using the networks")
+
+	networkCmd.AddCommand(networkLsCmd)
+	networkCmd.AddCommand(networkCreateCmd)
+	networkCmd.AddCommand(networkRmCmd)
+	networkCmd.AddCommand(networkInspectCmd)
+	RootCmd.AddCommand(networkCmd)
+}
+
+func listNetworksOnHost(host *config.Host) *podman.NetworkListResult {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	result := &podman.NetworkListResult{Hostname: host.Name}
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if result.Networks, err = backend.ListNetworks(ctx); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func createNetworkOnHost(host *config.Host, opts podman.NetworkCreateOptions) (string, error) {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	return backend.CreateNetwork(ctx, opts)
+}
+
+func removeNetworksOnHost(host *config.Host, names []string, force bool) (string, error) {
+	ctx, cancel := commandContext(60 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if err := backend.RemoveNetwork(ctx, name, force); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return strings.Join(names, " "), nil
+}
+
+func inspectNetworkOnHost(host *config.Host, name string) (*podman.Network, error) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return backend.InspectNetwork(ctx, name)
+}
+
+func displayNetworkTable(results []*podman.NetworkListResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Host", "Network ID", "Name", "Driver", "Subnets"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+
+	for _, result := range results {
+		if result.Error != "" {
+			table.Append([]string{result.Hostname, "ERROR", result.Error, "", ""})
+			continue
+		}
+
+		for _, network := range result.Networks {
+			table.Append([]string{
+				result.Hostname,
+				network.ShortID(),
+				network.Name,
+				network.Driver,
+				network.SubnetsString(),
+			})
+		}
+	}
+
+	table.Render()
+}
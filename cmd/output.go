@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+// linePrefixWriter writes every line it receives to w prefixed with prefix.
+// Whole lines are written under mu, so writers sharing mu can be used by
+// concurrent hosts without interleaving their output within a line.
+type linePrefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newLinePrefixWriter(mu *sync.Mutex, w io.Writer, prefix string) *linePrefixWriter {
+	return &linePrefixWriter{mu: mu, w: w, prefix: prefix}
+}
+
+func (p *linePrefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes a trailing partial line, if any
+func (p *linePrefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *linePrefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := io.WriteString(p.w, p.prefix); err != nil {
+		return err
+	}
+	_, err := p.w.Write(line)
+	return err
+}
+
+// shortID returns the first 12 characters of an object ID
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// formatTime formats a timestamp for tables, or "" if it is unset
+func formatTime(t podman.Timestamp) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// formatSize formats a size in bytes with decimal units like podman, e.g.
+// "187 MB"
+func formatSize(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	suffixes := []string{"kB", "MB", "GB", "TB", "PB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.3g %s", value, suffixes[i])
+}
+
+func printJSON(v interface{}) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+// inspectEntry is the JSON output of an inspect command for one host
+type inspectEntry struct {
+	Host   string `json:"host"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// inspectEntries converts the results of an inspect command on every host
+// to its JSON output
+func inspectEntries[T any](results []hostResult[T]) []inspectEntry {
+	entries := make([]inspectEntry, len(results))
+	for i, r := range results {
+		entries[i].Host = r.Host.Name
+		if r.Err != nil {
+			entries[i].Error = r.Err.Error()
+			continue
+		}
+		entries[i].Result = r.Value
+	}
+	return entries
+}
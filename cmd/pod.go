@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+var podCmd = &cobra.Command{
+	Use:   "pod",
+	Short: "Manage pods across hosts",
+}
+
+var podLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list", "ps"},
+	Short:   "List pods on all hosts",
+	Long: `List the pods of all hosts in one table with a Host column.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
+		}
+
+		hostResults := fanOut(hosts, func(host *config.Host) (*podman.PodListResult, error) {
+			return listPodsOnHost(host), nil
+		})
+		results := make([]*podman.PodListResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
+
+		if jsonOutput(cmd) {
+			printJSON(results)
+		} else {
+			displayPodTable(results)
+		}
+		return summarize(listStatus(hostResults, func(r *podman.PodListResult) string { return r.Error }))
+	},
+}
+
+var podCreateCmd = &cobra.Command{
+	Use:   "create <host/group> <name>",
+	Short: "Create a pod on hosts",
+	Long: `Create an empty pod on every selected host. Containers join it with
+podman-swarm run <host/group> <image> --pod <name>.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		labels, _ := cmd.Flags().GetStringArray("label")
+		publish, _ := cmd.Flags().GetStringArray("publish")
+		networks, _ := cmd.Flags().GetStringArray("network")
+
+		create := podman.PodCreateOptions{Name: args[1], Publish: publish, Networks: networks}
+		var err error
+		if create.Labels, err = keyValues("label", labels); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return createPodOnHost(host, create)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var podStartCmd = &cobra.Command{
+	Use:   "start <host/group> <pod>...",
+	Short: "Start pods on hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPodAction(cmd, args, "start", func(ctx context.Context, backend podman.Backend, pod string) error {
+			return backend.StartPod(ctx, pod)
+		})
+	},
+}
+
+var podStopCmd = &cobra.Command{
+	Use:   "stop <host/group> <pod>...",
+	Short: "Stop pods on hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPodAction(cmd, args, "stop", func(ctx context.Context, backend podman.Backend, pod string) error {
+			return backend.StopPod(ctx, pod)
+		})
+	},
+}
+
+var podRmCmd = &cobra.Command{
+	Use:   "rm <host/group> <pod>...",
+	Short: "Remove pods from hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return runPodAction(cmd, args, "remove", func(ctx context.Context, backend podman.Backend, pod string) error {
+			return backend.RemovePod(ctx, pod, force)
+		})
+	},
+}
+
+var podInspectCmd = &cobra.Command{
+	Use:   "inspect <host/group> <pod>",
+	Short: "Display pod details on hosts",
+	Long:  `Inspect a pod on every selected host and display the results in JSON format, one entry per host.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (*podman.PodInspect, error) {
+			return inspectPodOnHost(host, args[1])
+		})
+		printJSON(inspectEntries(results))
+		return summarize(results)
+	},
+}
+
+func init() {
+	podLsCmd.Flags().Bool("json", false, "Output in JSON format")
+	podLsCmd.Flags().StringP("hosts", "l", "", "Only list pods on hosts matching this selector")
+	podCreateCmd.Flags().StringArray("label", nil, "Set a label as key=value (repeatable)")
+	podCreateCmd.Flags().StringArrayP("publish", "p", nil, "Publish a port, e.g. 8080:80 (repeatable)")
+	podCreateCmd.Flags().StringArray("network", nil, "Connect the pod to a network (repeatable)")
+	podRmCmd.Flags().BoolP("force", "f", false, "Stop and remove running containers of the pods")
+
+	podCmd.AddCommand(podLsCmd)
+	podCmd.AddCommand(podCreateCmd)
+	podCmd.AddCommand(podStartCmd)
+	podCmd.AddCommand(podStopCmd)
+	podCmd.AddCommand(podRmCmd)
+	podCmd.AddCommand(podInspectCmd)
+	RootCmd.AddCommand(podCmd)
+}
+
+// runPodAction applies action to the pods args[1:] on every host selected
+// by args[0]
+func runPodAction(cmd *cobra.Command, args []string, verb string, action func(ctx context.Context, backend podman.Backend, pod string) error) error {
+	cmd.SilenceUsage = true
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	hosts, err := targetHosts(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	results := fanOut(hosts, func(host *config.Host) (string, error) {
+		ctx, cancel := commandContext(60 * time.Second)
+		defer cancel()
+
+		backend, err := podmanBackend(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		for _, pod := range args[1:] {
+			if err := action(ctx, backend, pod); err != nil {
+				return "", fmt.Errorf("failed to %s %s: %w", verb, pod, err)
+			}
+		}
+		return strings.Join(args[1:], " "), nil
+	})
+	printHostResults(results)
+	return summarize(results)
+}
+
+func listPodsOnHost(host *config.Host) *podman.PodListResult {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	result := &podman.PodListResult{Hostname: host.Name}
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if result.Pods, err = backend.ListPods(ctx); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func createPodOnHost(host *config.Host, opts podman.PodCreateOptions) (string, error) {
+	ctx, cancel := commandContext(60 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	id, err := backend.CreatePod(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return shortID(id), nil
+}
+
+func inspectPodOnHost(host *config.Host, name string) (*podman.PodInspect, error) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return backend.InspectPod(ctx, name)
+}
+
+func displayPodTable(results []*podman.PodListResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Host", "Pod ID", "Name", "Status", "Created", "Containers"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+
+	for _, result := range results {
+		if result.Error != "" {
+			table.Append([]string{result.Hostname, "ERROR", result.Error, "", "", ""})
+			continue
+		}
+
+		for _, pod := range result.Pods {
+			table.Append([]string{
+				result.Hostname,
+				pod.ShortID(),
+				pod.Name,
+				pod.Status,
+				formatTime(pod.Created),
+				strconv.Itoa(len(pod.Containers)),
+			})
+		}
+	}
+
+	table.Render()
+}
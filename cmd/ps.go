@@ -1,82 +1,73 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
 	"github.com/ytnobody/podman-swarm/pkg/podman"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
 )
 
 var psCmd = &cobra.Command{
 	Use:   "ps",
 	Short: "Display container information list for all hosts",
-	Long:  `Execute podman ps -a on all hosts and aggregate results in table format.`,
+	Long: `Execute podman ps -a on all hosts and aggregate results in table format.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		jsonOutput, _ := cmd.Flags().GetBool("json")
-
-		results := make([]*podman.ContainerListResult, 0)
-		var mu sync.Mutex
-		var wg sync.WaitGroup
-
-		for _, host := range cfg.Hosts {
-			wg.Add(1)
-			go func(h config.Host) {
-				defer wg.Done()
-
-				result := listContainersOnHost(h)
-				mu.Lock()
-				results = append(results, result)
-				mu.Unlock()
-			}(host)
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
 		}
 
-		wg.Wait()
+		hostResults := fanOut(hosts, func(host *config.Host) (*podman.ContainerListResult, error) {
+			return listContainersOnHost(host), nil
+		})
+		results := make([]*podman.ContainerListResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
 
-		if jsonOutput {
+		if jsonOutput(cmd) {
 			displayPsJSON(results)
 		} else {
 			displayPsTable(results)
 		}
-		return nil
+		return summarize(listStatus(hostResults, func(r *podman.ContainerListResult) string { return r.Error }))
 	},
 }
 
 func init() {
 	psCmd.Flags().Bool("json", false, "Output in JSON format")
+	psCmd.Flags().StringP("hosts", "l", "", "Only list containers on hosts matching this selector")
 }
 
-func listContainersOnHost(host config.Host) *podman.ContainerListResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func listContainersOnHost(host *config.Host) *podman.ContainerListResult {
+	ctx, cancel := commandContext(10 * time.Second)
 	defer cancel()
 
-	client, err := ssh.NewClient(ssh.ClientConfig{
-		Host:       host.Address,
-		Port:       host.Port,
-		Username:   host.Username,
-		PrivateKey: host.PrivateKey,
-	})
+	result := &podman.ContainerListResult{Hostname: host.Name}
+	backend, err := podmanBackend(ctx, host)
 	if err != nil {
-		return &podman.ContainerListResult{
-			Hostname: host.Name,
-			Error:    err.Error(),
-		}
+		result.Error = err.Error()
+		return result
 	}
-	defer client.Close()
 
-	result, _ := podman.ListContainers(ctx, host.Name, client)
+	if result.Containers, err = backend.ListContainers(ctx); err != nil {
+		result.Error = err.Error()
+	}
 	return result
 }
 
@@ -95,11 +86,11 @@ func displayPsTable(results []*podman.ContainerListResult) {
 		for _, c := range result.Containers {
 			table.Append([]string{
 				result.Hostname,
-				c.ID[:12],
-				c.Name,
+				c.ShortID(),
+				c.Name(),
 				c.Image,
 				c.Status,
-				c.Ports,
+				c.PortsString(),
 			})
 		}
 	}
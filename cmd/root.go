@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
 )
 
 var RootCmd = &cobra.Command{
@@ -12,6 +13,15 @@ var RootCmd = &cobra.Command{
 	Long: `podman-swarm is a CLI tool for managing Podman containers across multiple remote hosts.
 It uses SSH as the sole communication channel and executes native podman commands
 directly on remote hosts.`,
+	// Errors are printed by Execute, which also maps them to exit statuses
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := useContext(); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("podman-swarm - Podman container swarm manager")
 		cmd.Help()
@@ -19,6 +29,13 @@ directly on remote hosts.`,
 }
 
 func init() {
+	RootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Inventory file (default: $PODMAN_SWARM_CONFIG, the current context or ~/.config/podman-swarm/hosts.yaml)")
+	RootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context to use instead of the current one")
+	RootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "Timeout for remote commands (default depends on the command)")
+	RootCmd.PersistentFlags().IntVar(&maxSessions, "max-sessions", ssh.DefaultMaxSessions, "Maximum concurrent SSH sessions per host")
+	RootCmd.PersistentFlags().IntVar(&parallel, "parallel", defaultParallel, "Maximum number of hosts operated on at once (0: no limit, 1: one host at a time)")
+	RootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Skip the remaining hosts after the first failure")
+
 	RootCmd.AddCommand(statusCmd)
 	RootCmd.AddCommand(psCmd)
 	RootCmd.AddCommand(inspectCmd)
@@ -1,66 +1,68 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
 )
 
 var runCmd = &cobra.Command{
 	Use:   "run <host/group> <image> [args...]",
 	Short: "Create and start containers",
-	Long:  `Execute podman run command remotely on specified host or group.`,
-	Args:  cobra.MinimumNArgs(2),
+	Long: `Execute podman run command remotely on specified host or group.
+The podman command is used on every host, including hosts with podman_backend: api,
+so that all options of podman run are available.`,
+	Args: cobra.MinimumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostOrGroup := args[0]
 		image := args[1]
 		podmanArgs := args[2:]
 
+		cmd.SilenceUsage = true
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		hosts := cfg.GetHostOrGroup(hostOrGroup)
-		if hosts == nil || len(hosts) == 0 {
-			return fmt.Errorf("host or group '%s' not found", hostOrGroup)
+		hosts, err := targetHosts(cfg, hostOrGroup)
+		if err != nil {
+			return err
 		}
 
-		for _, host := range hosts {
-			if err := runContainerOnHost(host, image, podmanArgs); err != nil {
-				fmt.Printf("Error on %s: %v\n", host.Name, err)
-			}
-		}
-		return nil
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return runContainerOnHost(host, image, podmanArgs)
+		})
+		printHostResults(results)
+		return summarize(results)
 	},
 }
 
-func runContainerOnHost(host *config.Host, image string, args []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func runContainerOnHost(host *config.Host, image string, args []string) (string, error) {
+	ctx, cancel := commandContext(30 * time.Second)
 	defer cancel()
 
-	client, err := ssh.NewClient(ssh.ClientConfig{
-		Host:       host.Address,
-		Port:       host.Port,
-		Username:   host.Username,
-		PrivateKey: host.PrivateKey,
-	})
+	client, err := connect(ctx, host)
 	if err != nil {
-		return fmt.Errorf("failed to connect to host: %w", err)
+		return "", fmt.Errorf("failed to connect to host: %w", err)
 	}
 	defer client.Close()
 
-	cmdStr := fmt.Sprintf("podman run %s %s", strings.Join(args, " "), image)
-	output, err := client.Execute(ctx, cmdStr)
-	if err != nil {
-		return err
+	if err := registryLogin(ctx, host, image); err != nil {
+		return "", err
 	}
+	return client.Execute(ctx, runCommand(image, args))
+}
+
+// runCommand builds "podman run [args...] image"
+func runCommand(image string, args []string) string {
+	return podman.Command(append(append([]string{"run"}, args...), image)...)
+}
 
-	fmt.Printf("[%s] %s\n", host.Name, output)
-	return nil
+func init() {
+	// Flags after the image belong to podman run
+	runCmd.Flags().SetInterspersed(false)
 }
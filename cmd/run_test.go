@@ -41,6 +41,12 @@ func TestRunContainerCommandOrder(t *testing.T) {
 			args:          []string{"--rm", "-it"},
 			expectedOrder: "--rm -it busybox",
 		},
+		{
+			name:          "arg with spaces",
+			image:         "alpine",
+			args:          []string{"-e", "MSG=hello world"},
+			expectedOrder: "podman run -e 'MSG=hello world' alpine",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -55,11 +61,7 @@ func TestRunContainerCommandOrder(t *testing.T) {
 			}
 
 			// Build the command string the same way runContainerOnHost does
-			cmdStr := "podman run " + strings.Join(tc.args, " ")
-			if len(tc.args) > 0 {
-				cmdStr += " "
-			}
-			cmdStr += tc.image
+			cmdStr := runCommand(tc.image, tc.args)
 
 			_, err := mockClient.Execute(context.Background(), cmdStr)
 			if err != nil {
@@ -117,11 +119,7 @@ func TestRunCommand_Integration(t *testing.T) {
 			}
 
 			// Simulate the command building logic from runContainerOnHost
-			cmdStr := "podman run "
-			if len(tc.args) > 0 {
-				cmdStr += strings.Join(tc.args, " ") + " "
-			}
-			cmdStr += tc.image
+			cmdStr := runCommand(tc.image, tc.args)
 
 			_, err := mockClient.Execute(context.Background(), cmdStr)
 
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"golang.org/x/term"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage the encrypted secret store",
+	Long: `Manage named secrets in ~/.config/podman-swarm/secrets.enc, encrypted with a passphrase.
+The inventory refers to them as secret://<name>, e.g. "passphrase: secret://deploy-key".
+The passphrase is read from $` + config.SecretPassphraseEnv + ` or asked on the terminal.`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Add or replace a secret",
+	Long: `Add or replace a secret. Without a value it is asked on the terminal, or read from
+stdin when stdin is not a terminal. Prefer these to passing the value as an argument,
+which may be recorded in the shell history.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		store, err := config.OpenSecretStore(true)
+		if err != nil {
+			return err
+		}
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else if value, err = readSecretValue(args[0]); err != nil {
+			return err
+		}
+		if err := store.Set(args[0], value); err != nil {
+			return err
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Secret %s saved\n", args[0])
+		return nil
+	},
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print the value of a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		store, err := config.OpenSecretStore(false)
+		if err != nil {
+			return err
+		}
+		value, err := store.Get(args[0])
+		if err != nil {
+			return secretNotFound(err)
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:   "rm <name>...",
+	Short: "Delete secrets",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		store, err := config.OpenSecretStore(false)
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			if err := store.Remove(name); err != nil {
+				return secretNotFound(err)
+			}
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		for _, name := range args {
+			fmt.Printf("Secret %s deleted\n", name)
+		}
+		return nil
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names of the secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		store, err := config.OpenSecretStore(false)
+		if err != nil {
+			return err
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Updated"})
+		table.SetBorder(true)
+		table.SetRowLine(false)
+		for _, name := range store.Names() {
+			table.Append([]string{name, store.Updated(name).Local().Format(time.RFC3339)})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+// readSecretValue asks for the value of a secret on the terminal, or reads
+// it from stdin
+func readSecretValue(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.TrimRight(data, "\r\n")), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value: %w", err)
+	}
+	return string(value), nil
+}
+
+// secretNotFound makes unknown secrets exit with exitNotFound
+func secretNotFound(err error) error {
+	var notFound *config.SecretNotFoundError
+	if errors.As(err, &notFound) {
+		return &exitError{code: exitNotFound, err: err}
+	}
+	return err
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretGetCmd)
+	secretCmd.AddCommand(secretRmCmd)
+	secretCmd.AddCommand(secretListCmd)
+	RootCmd.AddCommand(secretCmd)
+}
@@ -1,47 +1,48 @@
 package cmd
 
 import (
-	"context"
-	"sync"
+	"fmt"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
 	"os"
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Display status of all hosts",
-	Long:  `Attempt SSH connections to all hosts in parallel and display their status in table format.`,
+	Long: `Attempt SSH connections to all hosts in parallel and display their status in table format.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		results := make([]statusResult, 0)
-		var mu sync.Mutex
-		var wg sync.WaitGroup
-
-		for _, host := range cfg.Hosts {
-			wg.Add(1)
-			go func(h config.Host) {
-				defer wg.Done()
-
-				result := checkHostStatus(h)
-				mu.Lock()
-				results = append(results, result)
-				mu.Unlock()
-			}(host)
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
 		}
 
-		wg.Wait()
+		hostResults := fanOut(hosts, func(host *config.Host) (statusResult, error) {
+			return checkHostStatus(host), nil
+		})
+		results := make([]statusResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
 
+		if activeContext != nil {
+			fmt.Printf("Context: %s (%s)\n", activeContext.Name, config.ConfigPath())
+		}
 		displayStatusTable(results)
-		return nil
+		return summarize(listStatus(hostResults, func(r statusResult) string { return r.Error }))
 	},
 }
 
@@ -51,26 +52,18 @@ type statusResult struct {
 	Error  string
 }
 
-func checkHostStatus(host config.Host) statusResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func init() {
+	statusCmd.Flags().StringP("hosts", "l", "", "Only check hosts matching this selector")
+}
+
+func checkHostStatus(host *config.Host) statusResult {
+	ctx, cancel := commandContext(10 * time.Second)
 	defer cancel()
 
-	client, err := ssh.NewClient(ssh.ClientConfig{
-		Host:       host.Address,
-		Port:       host.Port,
-		Username:   host.Username,
-		PrivateKey: host.PrivateKey,
-	})
-	if err != nil {
-		return statusResult{
-			Host:   host.Name,
-			Status: "DOWN",
-			Error:  err.Error(),
-		}
+	backend, err := podmanBackend(ctx, host)
+	if err == nil {
+		err = backend.Ping(ctx)
 	}
-	defer client.Close()
-
-	_, err = client.Execute(ctx, "podman info > /dev/null 2>&1")
 	if err != nil {
 		return statusResult{
 			Host:   host.Name,
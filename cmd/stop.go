@@ -1,13 +1,10 @@
 package cmd
 
 import (
-	"context"
-	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ytnobody/podman-swarm/pkg/config"
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
 )
 
 var stopCmd = &cobra.Command{
@@ -19,46 +16,36 @@ var stopCmd = &cobra.Command{
 		hostOrGroup := args[0]
 		containerID := args[1]
 
+		cmd.SilenceUsage = true
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		hosts := cfg.GetHostOrGroup(hostOrGroup)
-		if hosts == nil || len(hosts) == 0 {
-			return fmt.Errorf("host or group '%s' not found", hostOrGroup)
+		hosts, err := targetHosts(cfg, hostOrGroup)
+		if err != nil {
+			return err
 		}
 
-		for _, host := range hosts {
-			if err := stopContainerOnHost(host, containerID); err != nil {
-				fmt.Printf("Error on %s: %v\n", host.Name, err)
-			}
-		}
-		return nil
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return stopContainerOnHost(host, containerID)
+		})
+		printHostResults(results)
+		return summarize(results)
 	},
 }
 
-func stopContainerOnHost(host *config.Host, containerID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func stopContainerOnHost(host *config.Host, containerID string) (string, error) {
+	ctx, cancel := commandContext(10 * time.Second)
 	defer cancel()
 
-	client, err := ssh.NewClient(ssh.ClientConfig{
-		Host:       host.Address,
-		Port:       host.Port,
-		Username:   host.Username,
-		PrivateKey: host.PrivateKey,
-	})
+	backend, err := podmanBackend(ctx, host)
 	if err != nil {
-		return fmt.Errorf("failed to connect to host: %w", err)
+		return "", err
 	}
-	defer client.Close()
-
-	cmdStr := fmt.Sprintf("podman stop %s", containerID)
-	output, err := client.Execute(ctx, cmdStr)
-	if err != nil {
-		return err
+	if err := backend.StopContainer(ctx, containerID); err != nil {
+		return "", err
 	}
-
-	fmt.Printf("[%s] %s\n", host.Name, output)
-	return nil
+	return containerID, nil
 }
@@ -0,0 +1,45 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	"golang.org/x/term"
+)
+
+// watchWindowSize reports the size of the terminal fd every time the window
+// is resized, until stop is called
+func watchWindowSize(fd int) (<-chan ssh.WindowSize, func()) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+
+	sizes := make(chan ssh.WindowSize, 1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-winch:
+				width, height, err := term.GetSize(fd)
+				if err != nil {
+					continue
+				}
+				select {
+				case sizes <- ssh.WindowSize{Width: width, Height: height}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	return sizes, func() {
+		signal.Stop(winch)
+		close(done)
+	}
+}
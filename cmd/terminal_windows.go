@@ -0,0 +1,12 @@
+//go:build windows
+
+package cmd
+
+import (
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// watchWindowSize is a no-op on Windows, which has no SIGWINCH
+func watchWindowSize(fd int) (<-chan ssh.WindowSize, func()) {
+	return nil, func() {}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Manage volumes across hosts",
+}
+
+var volumeLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List volumes on all hosts",
+	Long: `List the volumes of all hosts in one table with a Host column.
+Use --hosts to limit the hosts, e.g. --hosts web or --hosts env=prod.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		hosts := cfg.AllHosts()
+		if selector := hostSelector(cmd); selector != "" {
+			if hosts, err = targetHosts(cfg, selector); err != nil {
+				return err
+			}
+		}
+
+		hostResults := fanOut(hosts, func(host *config.Host) (*podman.VolumeListResult, error) {
+			return listVolumesOnHost(host), nil
+		})
+		results := make([]*podman.VolumeListResult, 0, len(hostResults))
+		for _, r := range hostResults {
+			results = append(results, r.Value)
+		}
+
+		if jsonOutput(cmd) {
+			printJSON(results)
+		} else {
+			displayVolumeTable(results)
+		}
+		return summarize(listStatus(hostResults, func(r *podman.VolumeListResult) string { return r.Error }))
+	},
+}
+
+var volumeCreateCmd = &cobra.Command{
+	Use:   "create <host/group> <name>",
+	Short: "Create a volume on hosts",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		driver, _ := cmd.Flags().GetString("driver")
+		labels, _ := cmd.Flags().GetStringArray("label")
+		opts, _ := cmd.Flags().GetStringArray("opt")
+
+		create := podman.VolumeCreateOptions{Name: args[1], Driver: driver}
+		var err error
+		if create.Labels, err = keyValues("label", labels); err != nil {
+			return err
+		}
+		if create.Options, err = keyValues("option", opts); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return createVolumeOnHost(host, create)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var volumeRmCmd = &cobra.Command{
+	Use:   "rm <host/group> <name>...",
+	Short: "Remove volumes from hosts",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (string, error) {
+			return removeVolumesOnHost(host, args[1:], force)
+		})
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+var volumeInspectCmd = &cobra.Command{
+	Use:   "inspect <host/group> <name>",
+	Short: "Display volume details on hosts",
+	Long:  `Inspect a volume on every selected host and display the results in JSON format, one entry per host.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		hosts, err := targetHosts(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		results := fanOut(hosts, func(host *config.Host) (*podman.Volume, error) {
+			return inspectVolumeOnHost(host, args[1])
+		})
+		printJSON(inspectEntries(results))
+		return summarize(results)
+	},
+}
+
+func init() {
+	volumeLsCmd.Flags().Bool("json", false, "Output in JSON format")
+	volumeLsCmd.Flags().StringP("hosts", "l", "", "Only list volumes on hosts matching this selector")
+	volumeCreateCmd.Flags().String("driver", "", "Volume driver (default local)")
+	volumeCreateCmd.Flags().StringArray("label", nil, "Set a label as key=value (repeatable)")
+	volumeCreateCmd.Flags().StringArray("opt", nil, "Set a driver option as key=value, e.g. type=tmpfs (repeatable)")
+	volumeRmCmd.Flags().BoolP("force", "f", false, "Also remove containers using the volumes")
+
+	volumeCmd.AddCommand(volumeLsCmd)
+	volumeCmd.AddCommand(volumeCreateCmd)
+	volumeCmd.AddCommand(volumeRmCmd)
+	volumeCmd.AddCommand(volumeInspectCmd)
+	RootCmd.AddCommand(volumeCmd)
+}
+
+// keyValues parses key=value arguments of a flag
+func keyValues(kind string, args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid %s %q (expected key=value)", kind, arg)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func listVolumesOnHost(host *config.Host) *podman.VolumeListResult {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	result := &podman.VolumeListResult{Hostname: host.Name}
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if result.Volumes, err = backend.ListVolumes(ctx); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func createVolumeOnHost(host *config.Host, opts podman.VolumeCreateOptions) (string, error) {
+	ctx, cancel := commandContext(30 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	return backend.CreateVolume(ctx, opts)
+}
+
+func removeVolumesOnHost(host *config.Host, names []string, force bool) (string, error) {
+	ctx, cancel := commandContext(60 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if err := backend.RemoveVolume(ctx, name, force); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return strings.Join(names, " "), nil
+}
+
+func inspectVolumeOnHost(host *config.Host, name string) (*podman.Volume, error) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	backend, err := podmanBackend(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return backend.InspectVolume(ctx, name)
+}
+
+func displayVolumeTable(results []*podman.VolumeListResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Host", "Name", "Driver", "Mountpoint", "Created"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+
+	for _, result := range results {
+		if result.Error != "" {
+			table.Append([]string{result.Hostname, "ERROR", result.Error, "", ""})
+			continue
+		}
+
+		for _, volume := range result.Volumes {
+			table.Append([]string{
+				result.Hostname,
+				volume.Name,
+				volume.Driver,
+				volume.Mountpoint,
+				formatTime(volume.CreatedAt),
+			})
+		}
+	}
+
+	table.Render()
+}
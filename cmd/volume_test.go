@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+// TestParseVolumeRef verifies splitting of host:volume arguments
+func TestParseVolumeRef(t *testing.T) {
+	host, volume, err := parseVolumeRef("web1:data")
+	if err != nil || host != "web1" || volume != "data" {
+		t.Errorf("unexpected result: %q, %q, %v", host, volume, err)
+	}
+	for _, ref := range []string{"data", ":data", "web1:"} {
+		if _, _, err := parseVolumeRef(ref); err == nil {
+			t.Errorf("expected an error for %q", ref)
+		}
+	}
+}
+
+// TestKeyValues verifies parsing of --label and --opt values
+func TestKeyValues(t *testing.T) {
+	got, err := keyValues("label", []string{"app=db", "empty=", "url=a=b"})
+	want := map[string]string{"app": "db", "empty": "", "url": "a=b"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+	if _, err := keyValues("label", []string{"app"}); err == nil {
+		t.Error("expected an error without =")
+	}
+}
+
+// TestWriteFileAtomic verifies that a failed write keeps the existing file
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "backup.tar")
+	if err := os.WriteFile(file, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeFileAtomic(file, func(w io.Writer) error {
+		fmt.Fprint(w, "partial")
+		return errors.New("export failed")
+	})
+	if err == nil {
+		t.Error("expected the write error")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "old" {
+		t.Errorf("existing file was changed: %q", data)
+	}
+
+	if err := writeFileAtomic(file, func(w io.Writer) error {
+		_, err := fmt.Fprint(w, "new")
+		return err
+	}); err != nil {
+		t.Fatalf("writeFileAtomic should succeed, got: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "new" {
+		t.Errorf("unexpected content: %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files were left: %v", entries)
+	}
+}
+
+// TestInspectEntries verifies that failed hosts carry only their error
+func TestInspectEntries(t *testing.T) {
+	results := []hostResult[*podman.Volume]{
+		{Host: &config.Host{Name: "host1"}, Value: &podman.Volume{Name: "data"}},
+		{Host: &config.Host{Name: "host2"}, Err: errors.New("no such volume")},
+	}
+
+	data, err := json.Marshal(inspectEntries(results))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `{"host":"host2","error":"no such volume"}`) ||
+		!strings.Contains(string(data), `"result":{"Name":"data"`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ytnobody/podman-swarm/pkg/config"
+	"github.com/ytnobody/podman-swarm/pkg/podman"
+)
+
+var volumeBackupCmd = &cobra.Command{
+	Use:   "backup <host> <volume> -o <file>",
+	Short: "Save the contents of a volume to a local tarball",
+	Long: `Stream podman volume export from a host into a local tar file. The file is only replaced
+once the export succeeded. Use -o - to write to stdout.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		cmd.SilenceUsage = true
+
+		host, err := lookupHost(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext(60 * time.Minute)
+		defer cancel()
+
+		client, err := connect(ctx, host)
+		if err != nil {
+			return fmt.Errorf("failed to connect to host: %w", err)
+		}
+
+		export := func(w io.Writer) error {
+			return volumeNotFound(podman.ExportVolume(ctx, client, args[1], w), args[1], host)
+		}
+		if output == "-" {
+			return export(os.Stdout)
+		}
+		return writeFileAtomic(output, export)
+	},
+}
+
+var volumeRestoreCmd = &cobra.Command{
+	Use:   "restore <host> <volume> <file>",
+	Short: "Restore the contents of a volume from a local tarball",
+	Long: `Stream a local tar file into podman volume import on a host. The volume is created if it
+does not exist; files already in it are kept unless the tarball replaces them. Use - to read
+from stdin.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostName, volume, file := args[0], args[1], args[2]
+		cmd.SilenceUsage = true
+
+		host, err := lookupHost(hostName)
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		ctx, cancel := commandContext(60 * time.Minute)
+		defer cancel()
+
+		client, err := connect(ctx, host)
+		if err != nil {
+			return fmt.Errorf("failed to connect to host: %w", err)
+		}
+
+		if err := podman.ImportVolume(ctx, client, volume, r); err != nil {
+			return err
+		}
+		fmt.Printf("[%s] restored %s\n", host.Name, volume)
+		return nil
+	},
+}
+
+var volumeCopyCmd = &cobra.Command{
+	Use:   "copy <src-host>:<volume> <dst-host>:<volume>",
+	Short: "Copy the contents of a volume between hosts",
+	Long: `Stream podman volume export on the source host through this machine into podman volume
+import on the destination host, without temporary files. The destination volume is created if
+it does not exist.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcName, srcVolume, err := parseVolumeRef(args[0])
+		if err != nil {
+			return err
+		}
+		dstName, dstVolume, err := parseVolumeRef(args[1])
+		if err != nil {
+			return err
+		}
+		if srcName == dstName && srcVolume == dstVolume {
+			return fmt.Errorf("source and destination are the same volume")
+		}
+		cmd.SilenceUsage = true
+
+		src, err := lookupHost(srcName)
+		if err != nil {
+			return err
+		}
+		dst, err := lookupHost(dstName)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext(60 * time.Minute)
+		defer cancel()
+
+		srcClient, err := connect(ctx, src)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", src.Name, err)
+		}
+
+		results, err := broadcast([]*config.Host{dst}, func(w io.Writer) error {
+			return podman.ExportVolume(ctx, srcClient, srcVolume, w)
+		}, func(host *config.Host, r io.Reader) (string, error) {
+			client, err := connect(ctx, host)
+			if err != nil {
+				return "", fmt.Errorf("failed to connect to host: %w", err)
+			}
+			if err := podman.ImportVolume(ctx, client, dstVolume, r); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("copied %s from %s", dstVolume, args[0]), nil
+		})
+		if err != nil {
+			return volumeNotFound(fmt.Errorf("failed to export from %s: %w", src.Name, err), srcVolume, src)
+		}
+		printHostResults(results)
+		return summarize(results)
+	},
+}
+
+func init() {
+	volumeBackupCmd.Flags().StringP("output", "o", "", "File to write the tarball to, or - for stdout")
+	volumeBackupCmd.MarkFlagRequired("output")
+
+	volumeCmd.AddCommand(volumeBackupCmd)
+	volumeCmd.AddCommand(volumeRestoreCmd)
+	volumeCmd.AddCommand(volumeCopyCmd)
+}
+
+// lookupHost returns the inventory host with the given name
+func lookupHost(name string) (*config.Host, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	host := cfg.GetHostByName(name)
+	if host == nil {
+		return nil, &exitError{code: exitNotFound, err: fmt.Errorf("host '%s' not found in configuration", name)}
+	}
+	return host, nil
+}
+
+// parseVolumeRef splits a "host:volume" argument of volume copy
+func parseVolumeRef(ref string) (host, volume string, err error) {
+	host, volume, ok := strings.Cut(ref, ":")
+	if !ok || host == "" || volume == "" {
+		return "", "", fmt.Errorf("invalid volume %q (expected host:volume)", ref)
+	}
+	return host, volume, nil
+}
+
+// volumeNotFound makes errors about a missing volume exit with
+// exitNotFound
+func volumeNotFound(err error, volume string, host *config.Host) error {
+	if err != nil && podman.IsNotFound(err) {
+		return &exitError{code: exitNotFound, err: fmt.Errorf("volume %s not found on %s: %w", volume, host.Name, err)}
+	}
+	return err
+}
+
+// writeFileAtomic writes file through a temporary file in the same
+// directory, so that a failed write leaves an existing file untouched
+func writeFileAtomic(file string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
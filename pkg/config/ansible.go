@@ -0,0 +1,452 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleInventory is an Ansible inventory in file order
+type ansibleInventory struct {
+	hosts    []string
+	hostVars map[string]map[string]string
+
+	groups        []string
+	groupHosts    map[string][]string
+	groupChildren map[string][]string
+	groupVars     map[string]map[string]string
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{
+		hostVars:      map[string]map[string]string{},
+		groupHosts:    map[string][]string{},
+		groupChildren: map[string][]string{},
+		groupVars:     map[string]map[string]string{},
+	}
+}
+
+func (inv *ansibleInventory) addHost(name string, vars map[string]string) {
+	if _, ok := inv.hostVars[name]; !ok {
+		inv.hosts = append(inv.hosts, name)
+		inv.hostVars[name] = map[string]string{}
+	}
+	for k, v := range vars {
+		inv.hostVars[name][k] = v
+	}
+}
+
+func (inv *ansibleInventory) addGroup(name string) {
+	if _, ok := inv.groupVars[name]; !ok {
+		inv.groups = append(inv.groups, name)
+		inv.groupVars[name] = map[string]string{}
+	}
+}
+
+func (inv *ansibleInventory) addGroupHost(group, host string) {
+	inv.addGroup(group)
+	for _, h := range inv.groupHosts[group] {
+		if h == host {
+			return
+		}
+	}
+	inv.groupHosts[group] = append(inv.groupHosts[group], host)
+}
+
+func (inv *ansibleInventory) addGroupChild(group, child string) {
+	inv.addGroup(group)
+	inv.addGroup(child)
+	inv.groupChildren[group] = append(inv.groupChildren[group], child)
+}
+
+// ImportAnsible converts an Ansible inventory file, in INI or YAML format,
+// into a configuration. Connection variables (ansible_host, ansible_port,
+// ansible_user, ansible_ssh_private_key_file and ProxyJump in
+// ansible_ssh_common_args) become host settings and other variables become
+// labels; vars of the all group become defaults. Variables that cannot be
+// converted are returned as warnings.
+func ImportAnsible(path string) (*Config, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var inv *ansibleInventory
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".json":
+		inv, err = parseAnsibleYAML(data)
+	default:
+		inv, err = parseAnsibleINI(data)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg, warnings := inv.config()
+	return cfg, warnings, nil
+}
+
+// config converts the inventory
+func (inv *ansibleInventory) config() (*Config, []string) {
+	var warnings []string
+	convert := func(vars map[string]string, where string) Host {
+		var h Host
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if w := applyAnsibleVar(&h, k, vars[k]); w != "" {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", where, w))
+			}
+		}
+		return h
+	}
+
+	cfg := &Config{}
+	cfg.Defaults = convert(inv.groupVars["all"], "group all")
+
+	for _, name := range inv.hosts {
+		h := convert(inv.hostVars[name], "host "+name)
+		h.Name = name
+		if h.Address == "" {
+			// Ansible connects to the inventory hostname by default
+			h.Address = name
+		}
+		cfg.Hosts = append(cfg.Hosts, h)
+	}
+
+	for _, name := range inv.groups {
+		// all is implicit in podman-swarm, and ungrouped hosts need no group
+		if name == "all" || name == "ungrouped" {
+			continue
+		}
+		g := HostGroup{
+			Name:  name,
+			Hosts: inv.groupHosts[name],
+			Vars:  convert(inv.groupVars[name], "group "+name),
+		}
+		for _, child := range inv.groupChildren[name] {
+			if child != "ungrouped" {
+				g.Children = append(g.Children, child)
+			}
+		}
+		cfg.Groups = append(cfg.Groups, g)
+	}
+	return cfg, warnings
+}
+
+var proxyJumpPattern = regexp.MustCompile(`(?:-J\s*|ProxyJump[= ]\s*)([^\s'"]+)`)
+
+// applyAnsibleVar sets the setting of h corresponding to an Ansible
+// variable, returning a warning if it cannot be converted
+func applyAnsibleVar(h *Host, key, value string) string {
+	switch key {
+	case "ansible_host", "ansible_ssh_host":
+		h.Address = value
+	case "ansible_port", "ansible_ssh_port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Sprintf("invalid %s %q", key, value)
+		}
+		h.Port = port
+	case "ansible_user", "ansible_ssh_user":
+		h.Username = value
+	case "ansible_ssh_private_key_file", "ansible_private_key_file":
+		h.PrivateKey = value
+	case "ansible_ssh_common_args", "ansible_ssh_extra_args":
+		m := proxyJumpPattern.FindStringSubmatch(value)
+		if m == nil {
+			return fmt.Sprintf("%s ignored", key)
+		}
+		for _, spec := range strings.Split(m[1], ",") {
+			hop, err := parseJumpSpec(spec)
+			if err != nil {
+				return err.Error()
+			}
+			h.Jump = append(h.Jump, hop)
+		}
+	default:
+		if strings.HasPrefix(key, "ansible_") {
+			return fmt.Sprintf("%s ignored", key)
+		}
+		if h.Labels == nil {
+			h.Labels = map[string]string{}
+		}
+		h.Labels[key] = value
+	}
+	return ""
+}
+
+// parseAnsibleINI parses an inventory in Ansible's INI format
+func parseAnsibleINI(data []byte) (*ansibleInventory, error) {
+	inv := newAnsibleInventory()
+	group, kind := "ungrouped", ""
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section %q", lineNo, line)
+			}
+			group, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			switch kind {
+			case "", "vars", "children":
+			default:
+				return nil, fmt.Errorf("line %d: unknown section type %q", lineNo, kind)
+			}
+			inv.addGroup(group)
+			continue
+		}
+
+		fields, err := splitAnsibleFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value", lineNo)
+			}
+			inv.groupVars[group][strings.TrimSpace(key)] = unquoteAnsible(strings.TrimSpace(value))
+		case "children":
+			inv.addGroupChild(group, fields[0])
+		default:
+			vars := map[string]string{}
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, field)
+				}
+				vars[key] = value
+			}
+			hosts, err := expandHostPattern(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			for _, host := range hosts {
+				inv.addHost(host, vars)
+				inv.addGroupHost(group, host)
+			}
+		}
+	}
+	return inv, scanner.Err()
+}
+
+// splitAnsibleFields splits a host line at whitespace, keeping quoted
+// values together and removing their quotes
+func splitAnsibleFields(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		case r == '#' && !inField:
+			// Trailing comment
+			return fields, nil
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func unquoteAnsible(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+var hostRangePattern = regexp.MustCompile(`\[([0-9]+|[a-z]):([0-9]+|[a-z])\]`)
+
+// expandHostPattern expands a host range like "web[01:03]" or "db-[a:c]"
+func expandHostPattern(pattern string) ([]string, error) {
+	loc := hostRangePattern.FindStringSubmatchIndex(pattern)
+	if loc == nil {
+		return []string{pattern}, nil
+	}
+	prefix, suffix := pattern[:loc[0]], pattern[loc[1]:]
+	from, to := pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]]
+
+	var items []string
+	if a, err := strconv.Atoi(from); err == nil {
+		b, err := strconv.Atoi(to)
+		if err != nil || b < a {
+			return nil, fmt.Errorf("invalid host range %q", pattern)
+		}
+		for i := a; i <= b; i++ {
+			// A leading zero pads every number to the width of the start
+			if len(from) > 1 && from[0] == '0' {
+				items = append(items, fmt.Sprintf("%0*d", len(from), i))
+			} else {
+				items = append(items, strconv.Itoa(i))
+			}
+		}
+	} else {
+		if len(to) != 1 || to[0] < from[0] {
+			return nil, fmt.Errorf("invalid host range %q", pattern)
+		}
+		for c := from[0]; c <= to[0]; c++ {
+			items = append(items, string(c))
+		}
+	}
+
+	var hosts []string
+	for _, item := range items {
+		rest, err := expandHostPattern(suffix)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rest {
+			hosts = append(hosts, prefix+item+r)
+		}
+	}
+	return hosts, nil
+}
+
+// parseAnsibleYAML parses an inventory in Ansible's YAML format
+func parseAnsibleYAML(data []byte) (*ansibleInventory, error) {
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := newAnsibleInventory()
+	groups, err := yamlPairs(root, "inventory")
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if err := inv.parseYAMLGroup(g.key, g.value); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func (inv *ansibleInventory) parseYAMLGroup(name string, node *yaml.Node) error {
+	inv.addGroup(name)
+	if node == nil || node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: group %s: expected a mapping", node.Line, name)
+	}
+
+	hosts, err := yamlPairs(mappingValue(node, "hosts"), "hosts of group "+name)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		vars, err := yamlVars(h.value, "host "+h.key)
+		if err != nil {
+			return err
+		}
+		expanded, err := expandHostPattern(h.key)
+		if err != nil {
+			return err
+		}
+		for _, host := range expanded {
+			inv.addHost(host, vars)
+			inv.addGroupHost(name, host)
+		}
+	}
+
+	vars, err := yamlVars(mappingValue(node, "vars"), "vars of group "+name)
+	if err != nil {
+		return err
+	}
+	for k, v := range vars {
+		inv.groupVars[name][k] = v
+	}
+
+	children, err := yamlPairs(mappingValue(node, "children"), "children of group "+name)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		inv.addGroupChild(name, child.key)
+		if err := inv.parseYAMLGroup(child.key, child.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlPair is an entry of a YAML mapping
+type yamlPair struct {
+	key   string
+	value *yaml.Node
+}
+
+// yamlPairs returns the entries of a mapping node in document order
+func yamlPairs(node *yaml.Node, where string) ([]yamlPair, error) {
+	if node == nil || node.Tag == "!!null" {
+		return nil, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: %s: expected a mapping", node.Line, where)
+	}
+	pairs := make([]yamlPair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, yamlPair{key: node.Content[i].Value, value: node.Content[i+1]})
+	}
+	return pairs, nil
+}
+
+// yamlVars returns the variables of a mapping node; structured values are
+// rejected
+func yamlVars(node *yaml.Node, where string) (map[string]string, error) {
+	pairs, err := yamlPairs(node, where)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for _, p := range pairs {
+		if p.value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: %s: variable %s is not a scalar", p.value.Line, where, p.key)
+		}
+		if p.value.Tag != "!!null" {
+			vars[p.key] = p.value.Value
+		}
+	}
+	return vars, nil
+}
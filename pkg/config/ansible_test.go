@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// importTestInventory writes content to a temporary file and imports it
+func importTestInventory(t *testing.T, name, content string) (*Config, []string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write inventory: %v", err)
+	}
+	cfg, warnings, err := ImportAnsible(path)
+	if err != nil {
+		t.Fatalf("ImportAnsible should not fail: %v", err)
+	}
+	return cfg, warnings
+}
+
+// TestImportAnsible_INI verifies conversion of an INI inventory
+func TestImportAnsible_INI(t *testing.T) {
+	cfg, warnings := importTestInventory(t, "hosts", `
+bastion ansible_host=203.0.113.1
+
+[web]
+web[01:02] ansible_user=deploy env=prod
+canary ansible_port=2222 ansible_ssh_common_args='-o ProxyJump=ops@bastion:2200'  # comment
+
+[db]
+db1 ansible_python_interpreter=/usr/bin/python3
+
+[db:vars]
+ansible_user = "postgres"
+
+[prod:children]
+web
+db
+
+[all:vars]
+ansible_ssh_private_key_file=~/.ssh/id_ed25519
+`)
+
+	if cfg.Defaults.PrivateKey != "~/.ssh/id_ed25519" {
+		t.Errorf("all:vars should become defaults, got: %+v", cfg.Defaults)
+	}
+
+	var names []string
+	for _, h := range cfg.Hosts {
+		names = append(names, h.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"bastion", "web01", "web02", "canary", "db1"}) {
+		t.Errorf("unexpected hosts: %v", names)
+	}
+
+	web01 := cfg.Hosts[1]
+	if web01.Address != "web01" || web01.Username != "deploy" || web01.Labels["env"] != "prod" {
+		t.Errorf("unexpected web01: %+v", web01)
+	}
+	canary := cfg.Hosts[3]
+	if canary.Port != 2222 || len(canary.Jump) != 1 || canary.Jump[0].Address != "bastion" || canary.Jump[0].Port != 2200 {
+		t.Errorf("unexpected canary: %+v", canary)
+	}
+
+	if len(cfg.Groups) != 3 {
+		t.Fatalf("expected 3 groups, got: %+v", cfg.Groups)
+	}
+	if cfg.Groups[1].Name != "db" || cfg.Groups[1].Vars.Username != "postgres" {
+		t.Errorf("db:vars should become group vars, got: %+v", cfg.Groups[1])
+	}
+	if !reflect.DeepEqual(cfg.Groups[2].Children, []string{"web", "db"}) {
+		t.Errorf("prod:children should become children, got: %+v", cfg.Groups[2])
+	}
+
+	if !reflect.DeepEqual(warnings, []string{"host db1: ansible_python_interpreter ignored"}) {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+// TestImportAnsible_YAML verifies conversion of a YAML inventory
+func TestImportAnsible_YAML(t *testing.T) {
+	cfg, _ := importTestInventory(t, "hosts.yml", `
+all:
+  vars:
+    ansible_user: ubuntu
+  children:
+    web:
+      hosts:
+        web2:
+          ansible_host: 10.0.0.2
+        web1:
+          ansible_host: 10.0.0.1
+          zone: 1
+      vars:
+        env: prod
+    db:
+      hosts:
+        db1:
+`)
+
+	if cfg.Defaults.Username != "ubuntu" {
+		t.Errorf("vars of all should become defaults, got: %+v", cfg.Defaults)
+	}
+	if len(cfg.Hosts) != 3 || cfg.Hosts[0].Name != "web2" || cfg.Hosts[1].Labels["zone"] != "1" || cfg.Hosts[2].Address != "db1" {
+		t.Errorf("unexpected hosts: %+v", cfg.Hosts)
+	}
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Vars.Labels["env"] != "prod" || len(cfg.Groups[1].Hosts) != 1 {
+		t.Errorf("unexpected groups: %+v", cfg.Groups)
+	}
+}
+
+// TestExpandHostPattern verifies Ansible host ranges
+func TestExpandHostPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"web", []string{"web"}},
+		{"web[1:3]", []string{"web1", "web2", "web3"}},
+		{"web[08:10].example.com", []string{"web08.example.com", "web09.example.com", "web10.example.com"}},
+		{"db-[a:b][1:2]", []string{"db-a1", "db-a2", "db-b1", "db-b2"}},
+	}
+
+	for _, tc := range testCases {
+		got, err := expandHostPattern(tc.pattern)
+		if err != nil || !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got: %v, %v", tc.pattern, tc.expected, got, err)
+		}
+	}
+}
@@ -5,64 +5,234 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/spf13/viper"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
 )
 
 type Host struct {
-	Name       string `mapstructure:"name" yaml:"name"`
-	Address    string `mapstructure:"address" yaml:"address"`
-	Port       int    `mapstructure:"port" yaml:"port"`
-	Username   string `mapstructure:"username" yaml:"username"`
-	PrivateKey string `mapstructure:"private_key" yaml:"private_key"`
+	Name            string `mapstructure:"name" yaml:"name,omitempty"`
+	Address         string `mapstructure:"address" yaml:"address,omitempty"`
+	Port            int    `mapstructure:"port" yaml:"port,omitempty"`
+	Username        string `mapstructure:"username" yaml:"username,omitempty"`
+	PrivateKey      string `mapstructure:"private_key" yaml:"private_key,omitempty"`
+	HostKey         string `mapstructure:"host_key" yaml:"host_key,omitempty"`
+	KnownHosts      string `mapstructure:"known_hosts" yaml:"known_hosts,omitempty"`
+	TrustOnFirstUse bool   `mapstructure:"trust_on_first_use" yaml:"trust_on_first_use,omitempty"`
+
+	Certificate       string   `mapstructure:"certificate" yaml:"certificate,omitempty"`
+	PassphraseEnv     string   `mapstructure:"passphrase_env" yaml:"passphrase_env,omitempty"`
+	PassphraseCommand string   `mapstructure:"passphrase_command" yaml:"passphrase_command,omitempty"`
+	AuthOrder         []string `mapstructure:"auth_order" yaml:"auth_order,omitempty"`
+	// Passphrase is the passphrase of an encrypted private_key, usually a
+	// secret://, env:// or cmd:// reference
+	Passphrase Secret `mapstructure:"passphrase" yaml:"passphrase,omitempty"`
+
+	Jump []JumpHost `mapstructure:"jump" yaml:"jump,omitempty"`
+
+	// Labels are free-form key/value pairs used by host selectors
+	Labels map[string]string `mapstructure:"labels" yaml:"labels,omitempty"`
+
+	// SSHConfigHost names a Host entry of the ssh_config file to take
+	// connection settings from. Settings in the inventory take precedence.
+	SSHConfigHost string `mapstructure:"ssh_config_host" yaml:"ssh_config_host,omitempty"`
+
+	// PodmanBackend selects how podman is driven on the host: "cli" (the
+	// default) runs the podman command, "api" uses its REST API socket
+	PodmanBackend string `mapstructure:"podman_backend" yaml:"podman_backend,omitempty"`
+	// PodmanSocket is the path of podman's API socket on the host. Defaults
+	// to the path reported by podman info.
+	PodmanSocket string `mapstructure:"podman_socket" yaml:"podman_socket,omitempty"`
+
+	// Registries are the credentials podman logs in with before pulling
+	// images, as "username:password" secrets keyed by registry, e.g.
+	// registry.example.com
+	Registries map[string]Secret `mapstructure:"registries" yaml:"registries,omitempty"`
 }
 
 type HostGroup struct {
-	Name  string   `mapstructure:"name" yaml:"name"`
-	Hosts []string `mapstructure:"hosts" yaml:"hosts"`
+	Name  string   `mapstructure:"name" yaml:"name,omitempty"`
+	Hosts []string `mapstructure:"hosts" yaml:"hosts,omitempty"`
+	// Children are groups whose hosts belong to this group as well
+	Children []string `mapstructure:"children" yaml:"children,omitempty"`
+	// Vars are settings for the hosts of this group that they do not set
+	// themselves
+	Vars Host `mapstructure:"vars" yaml:"vars,omitempty"`
 }
 
 type Config struct {
-	Hosts  []Host      `yaml:"hosts"`
-	Groups []HostGroup `yaml:"groups"`
+	// Defaults are settings for every host that neither the host nor its
+	// groups set
+	Defaults Host        `mapstructure:"defaults" yaml:"defaults,omitempty"`
+	Hosts    []Host      `mapstructure:"hosts" yaml:"hosts,omitempty"`
+	Groups   []HostGroup `mapstructure:"groups" yaml:"groups,omitempty"`
+	// SSHConfigFile is the OpenSSH client configuration read for
+	// ssh_config_host (default: ~/.ssh/config)
+	SSHConfigFile string `mapstructure:"ssh_config_file" yaml:"ssh_config_file,omitempty"`
+	// Include are globs of inventory files merged into this one, in order
+	Include []string `mapstructure:"include" yaml:"include,omitempty"`
+	// Dynamic are executables whose inventories are merged after the
+	// included files
+	Dynamic []DynamicInventory `mapstructure:"dynamic" yaml:"dynamic,omitempty"`
+
+	// origins tells where each setting of a host came from, by host name
+	origins map[string]map[string]string
+	// unknownSSHHosts are ssh_config_host aliases not found in SSHConfigFile
+	unknownSSHHosts []string
 }
 
-// Load loads the configuration from the default path
+// Load loads the configuration from the default path. It fails with a
+// *ValidationError listing every error found; warnings are ignored.
 func Load() (*Config, error) {
+	cfg, problems, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return resolve(cfg, problems)
+}
+
+// resolve fails with the errors among problems, or resolves the jump hosts
+// of cfg
+func resolve(cfg *Config, problems []Problem) (*Config, error) {
+	var errs []Problem
+	for _, p := range problems {
+		if p.Severity == Error {
+			errs = append(errs, p)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Problems: errs}
+	}
+
+	if err := cfg.resolveJumps(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// load reads and decodes the configuration and validates it
+func load() (*Config, []Problem, error) {
 	configPath := getConfigPath()
-	
+
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("config file not found at %s", configPath)
+		return nil, nil, fmt.Errorf("config file not found at %s", configPath)
 	}
 
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
 	}
+	return loadData(configPath, data)
+}
+
+// loadData decodes data as the contents of the configuration file at
+// configPath and validates it
+func loadData(configPath string, data []byte) (*Config, []Problem, error) {
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	settings, err := inventorySettings(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Decode once to learn the included inventories, again to learn the
+	// groups of the merged inventory, then with the settings inherited
+	// from group vars and defaults
+	sources := newSources(document{file: configPath, root: root}, settings)
+	var main Config
+	if err := decode(settings, &main); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := main.mergeSources(settings, sources, configPath); err != nil {
+		return nil, nil, err
+	}
+
+	var inventory Config
+	if err := decode(settings, &inventory); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	sshLayers, unknownSSHHosts, err := inventory.sshConfigVars()
+	if err != nil {
+		return nil, nil, err
+	}
+	origins := inventory.applyVars(settings, sshLayers)
 
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := decode(settings, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.origins = origins
+	cfg.unknownSSHHosts = unknownSSHHosts
 
 	for i := range cfg.Hosts {
-		if cfg.Hosts[i].PrivateKey == "" {
-			return nil, fmt.Errorf("private_key is empty for host %s", cfg.Hosts[i].Name)
-		}
 		cfg.Hosts[i].PrivateKey = expandPath(cfg.Hosts[i].PrivateKey)
+		cfg.Hosts[i].KnownHosts = expandPath(cfg.Hosts[i].KnownHosts)
+		cfg.Hosts[i].Certificate = expandPath(cfg.Hosts[i].Certificate)
 	}
 
-	return &cfg, nil
+	return &cfg, cfg.validate(sources), nil
+}
+
+// inventorySettings decodes the settings of an inventory document. They are
+// taken from the YAML node as written: viper would lowercase keys and split
+// them at dots, mangling label and registry names.
+func inventorySettings(root *yaml.Node) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	if root == nil {
+		return settings, nil
+	}
+	if err := root.Decode(&settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// decode decodes inventory settings into cfg
+func decode(settings map[string]interface{}, cfg *Config) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			jumpHostHook,
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           cfg,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(settings)
 }
 
 func getConfigPath() string {
+	if configPathOverride != "" {
+		return expandPath(configPathOverride)
+	}
 	if envPath := os.Getenv("PODMAN_SWARM_CONFIG"); envPath != "" {
 		return envPath
 	}
-	
+
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, ".config", "podman-swarm", "hosts.yaml")
 }
 
+func validAuthMethod(method string) bool {
+	switch method {
+	case "agent", "certificate", "key":
+		return true
+	}
+	return false
+}
+
+func validPodmanBackend(backend string) bool {
+	switch backend {
+	case "", "cli", "api":
+		return true
+	}
+	return false
+}
+
 func expandPath(path string) string {
 	if path == "" {
 		return path
@@ -87,20 +257,34 @@ func (c *Config) GetHostByName(name string) *Host {
 	return nil
 }
 
-// GetHostsByGroup returns all hosts in a group
+// AllHosts returns every host in the inventory
+func (c *Config) AllHosts() []*Host {
+	hosts := make([]*Host, 0, len(c.Hosts))
+	for i := range c.Hosts {
+		h := c.Hosts[i]
+		hosts = append(hosts, &h)
+	}
+	return hosts
+}
+
+// GetHostsByGroup returns all hosts in a group, including the hosts of its
+// child groups. The implicit group "all" contains every host.
 func (c *Config) GetHostsByGroup(groupName string) []*Host {
-	for _, g := range c.Groups {
-		if g.Name == groupName {
-			var hosts []*Host
-			for _, hostName := range g.Hosts {
-				if h := c.GetHostByName(hostName); h != nil {
-					hosts = append(hosts, h)
-				}
-			}
-			return hosts
+	g := c.group(groupName)
+	if g == nil {
+		if groupName == AllGroup && len(c.Hosts) > 0 {
+			return c.AllHosts()
 		}
+		return nil
 	}
-	return nil
+
+	var hosts []*Host
+	for _, hostName := range c.groupHostNames(g, nil, map[string]bool{}, map[string]bool{}) {
+		if h := c.GetHostByName(hostName); h != nil {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
 }
 
 // GetHostOrGroup returns hosts from a name (either single host or group)
@@ -0,0 +1,352 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestConfig writes content to a temporary hosts.yaml and loads it
+func loadTestConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("PODMAN_SWARM_CONFIG", path)
+	return Load()
+}
+
+// TestLoad_JumpChain verifies that jump hops referring to inventory hosts are
+// expanded, including the referenced host's own jump chain
+func TestLoad_JumpChain(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+hosts:
+  - name: edge
+    address: 203.0.113.1
+    username: ops
+    private_key: /keys/edge
+  - name: bastion
+    address: 10.0.0.1
+    username: jump
+    private_key: /keys/bastion
+    jump: [edge]
+  - name: app1
+    address: 10.0.1.10
+    username: ubuntu
+    private_key: /keys/app
+    jump:
+      - bastion
+      - admin@10.0.1.1:2222
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	app := cfg.GetHostByName("app1")
+	if len(app.Jump) != 3 {
+		t.Fatalf("expected 3 hops, got: %+v", app.Jump)
+	}
+	if app.Jump[0].Address != "203.0.113.1" || app.Jump[0].Username != "ops" {
+		t.Errorf("first hop should be edge, got: %+v", app.Jump[0])
+	}
+	if app.Jump[1].Address != "10.0.0.1" || app.Jump[1].PrivateKey != "/keys/bastion" {
+		t.Errorf("second hop should be bastion, got: %+v", app.Jump[1])
+	}
+	inline := app.Jump[2]
+	if inline.Address != "10.0.1.1" || inline.Port != 2222 || inline.Username != "admin" {
+		t.Errorf("third hop should be parsed from the string form, got: %+v", inline)
+	}
+	if inline.PrivateKey != "/keys/app" {
+		t.Errorf("inline hop should inherit the target's private key, got: %s", inline.PrivateKey)
+	}
+}
+
+// TestLoad_JumpCycle verifies that cyclic jump chains are rejected
+func TestLoad_JumpCycle(t *testing.T) {
+	_, err := loadTestConfig(t, `
+hosts:
+  - name: a
+    address: 10.0.0.1
+    private_key: /keys/a
+    jump: [b]
+  - name: b
+    address: 10.0.0.2
+    private_key: /keys/b
+    jump: [a]
+`)
+	if err == nil {
+		t.Fatal("Load should reject a jump cycle")
+	}
+}
+
+// TestParseJumpSpec verifies the string form of jump hops
+func TestParseJumpSpec(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		ref     string
+		user    string
+		address string
+		port    int
+	}{
+		{spec: "bastion", ref: "bastion"},
+		{spec: "ops@bastion.example.com", user: "ops", address: "bastion.example.com"},
+		{spec: "10.0.0.1:2222", address: "10.0.0.1", port: 2222},
+		{spec: "ops@[2001:db8::1]:22", user: "ops", address: "2001:db8::1", port: 22},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			hop, err := parseJumpSpec(tc.spec)
+			if err != nil {
+				t.Fatalf("parseJumpSpec should not fail: %v", err)
+			}
+			if hop.Ref != tc.ref || hop.Username != tc.user || hop.Address != tc.address || hop.Port != tc.port {
+				t.Errorf("unexpected hop: %+v", hop)
+			}
+		})
+	}
+}
+
+// TestGetHostsByGroup_Children verifies nested groups and the implicit all
+// group
+func TestGetHostsByGroup_Children(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+hosts:
+  - {name: web1, address: 10.0.0.1, username: u}
+  - {name: web2, address: 10.0.0.2, username: u}
+  - {name: db1, address: 10.0.0.3, username: u}
+  - {name: spare, address: 10.0.0.4, username: u}
+groups:
+  - name: web
+    hosts: [web1, web2]
+  - name: db
+    hosts: [db1]
+  - name: prod
+    hosts: [web1]
+    children: [web, db]
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	names := func(hosts []*Host) string {
+		var s []string
+		for _, h := range hosts {
+			s = append(s, h.Name)
+		}
+		return strings.Join(s, ",")
+	}
+	if got := names(cfg.GetHostsByGroup("prod")); got != "web1,web2,db1" {
+		t.Errorf("unexpected hosts in prod: %s", got)
+	}
+	if got := names(cfg.GetHostsByGroup("all")); got != "web1,web2,db1,spare" {
+		t.Errorf("implicit all should contain every host, got: %s", got)
+	}
+
+	cfg.Groups = append(cfg.Groups, HostGroup{Name: "all", Hosts: []string{"spare"}})
+	if got := names(cfg.GetHostsByGroup("all")); got != "spare" {
+		t.Errorf("an inventory group named all should take precedence, got: %s", got)
+	}
+}
+
+// TestLoad_GroupErrors verifies that unknown members and cycles are reported
+func TestLoad_GroupErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		groups   string
+		expected string
+	}{
+		{
+			name:     "unknown host",
+			groups:   "  - {name: web, hosts: [web1, wbe2]}",
+			expected: `group web refers to unknown host "wbe2"`,
+		},
+		{
+			name:     "unknown child",
+			groups:   "  - {name: prod, children: [web]}",
+			expected: `group prod refers to unknown child group "web"`,
+		},
+		{
+			name: "cycle",
+			groups: `  - {name: a, children: [b]}
+  - {name: b, children: [c]}
+  - {name: c, children: [a]}`,
+			expected: "group cycle: a -> b -> c -> a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, `
+hosts:
+  - {name: web1, address: 10.0.0.1, username: u}
+groups:
+`+tc.groups+"\n")
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+// TestLoad_Vars verifies that settings are inherited with host > group >
+// defaults precedence
+func TestLoad_Vars(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+defaults:
+  username: ops
+  port: 2222
+  private_key: /keys/default
+  trust_on_first_use: true
+  labels: {env: prod}
+hosts:
+  - name: bastion
+    address: 10.0.0.1
+  - name: web1
+    address: 10.0.1.1
+    port: 22
+    trust_on_first_use: false
+  - name: web2
+    address: 10.0.1.2
+    labels: {zone: a}
+groups:
+  - name: web
+    hosts: [web1, web2]
+    vars:
+      username: deploy
+      jump: [bastion]
+  - name: canary
+    hosts: [web2]
+    vars:
+      username: canary
+  - name: prod
+    children: [web, canary]
+    vars:
+      username: prod
+      private_key: /keys/prod
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	bastion := cfg.GetHostByName("bastion")
+	if bastion.Username != "ops" || bastion.Port != 2222 || len(bastion.Jump) != 0 {
+		t.Errorf("bastion should only get defaults, got: %+v", bastion)
+	}
+
+	web1 := cfg.GetHostByName("web1")
+	if web1.Username != "deploy" || web1.Port != 22 || web1.TrustOnFirstUse {
+		t.Errorf("web1 should keep its own settings over vars, got: %+v", web1)
+	}
+	if web1.PrivateKey != "/keys/prod" || len(web1.Jump) != 1 || web1.Jump[0].Address != "10.0.0.1" {
+		t.Errorf("web1 should inherit private_key and jump from its groups, got: %+v", web1)
+	}
+
+	web2 := cfg.GetHostByName("web2")
+	if web2.Username != "canary" {
+		t.Errorf("a later group of the same depth should take precedence, got: %s", web2.Username)
+	}
+	if web2.Labels["env"] != "prod" || web2.Labels["zone"] != "a" {
+		t.Errorf("labels should be merged, got: %v", web2.Labels)
+	}
+
+	settings, err := cfg.Settings("web2")
+	if err != nil {
+		t.Fatalf("Settings should not fail: %v", err)
+	}
+	origins := map[string]string{}
+	for _, s := range settings {
+		origins[s.Key] = s.Origin
+	}
+	expected := map[string]string{
+		"name":        OriginHost,
+		"username":    "group canary",
+		"port":        OriginDefaults,
+		"private_key": "group prod",
+		"jump":        "group web",
+		"labels.env":  OriginDefaults,
+		"labels.zone": OriginHost,
+	}
+	for key, want := range expected {
+		if origins[key] != want {
+			t.Errorf("%s should come from %s, got: %q", key, want, origins[key])
+		}
+	}
+}
+
+// TestLoad_DottedKeys verifies that label and registry names containing
+// dots are kept whole in defaults, group vars and hosts
+func TestLoad_DottedKeys(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+defaults:
+  labels:
+    app.tier: web
+  registries:
+    registry.example.com: env://EXAMPLE
+hosts:
+  - name: web1
+    address: 10.0.0.1
+    labels:
+      app.role: front
+groups:
+  - name: web
+    hosts: [web1]
+    vars:
+      labels:
+        topology.zone: a
+      registries:
+        quay.io: env://QUAY
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	host := cfg.GetHostByName("web1")
+	wantLabels := map[string]string{"app.tier": "web", "app.role": "front", "topology.zone": "a"}
+	if !reflect.DeepEqual(host.Labels, wantLabels) {
+		t.Errorf("unexpected labels: %v", host.Labels)
+	}
+	wantRegistries := map[string]Secret{"registry.example.com": "env://EXAMPLE", "quay.io": "env://QUAY"}
+	if !reflect.DeepEqual(host.Registries, wantRegistries) {
+		t.Errorf("unexpected registries: %v", host.Registries)
+	}
+}
+
+// TestLoad_Registries verifies that registry names keep their dots in
+// defaults, and that registries are inherited key by key
+func TestLoad_Registries(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+defaults:
+  registries:
+    docker.io: env://HUB
+hosts:
+  - name: web1
+    address: 10.0.0.1
+    registries:
+      docker.io: secret://hub-web1
+      registry.example.com: secret://example
+  - name: web2
+    address: 10.0.0.2
+groups:
+  - name: web
+    hosts: [web2]
+    vars:
+      registries:
+        quay.io: cmd://pass quay
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	want := map[string]Secret{"docker.io": "secret://hub-web1", "registry.example.com": "secret://example"}
+	if got := cfg.GetHostByName("web1").Registries; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected registries of web1: %v", got)
+	}
+	want = map[string]Secret{"docker.io": "env://HUB", "quay.io": "cmd://pass quay"}
+	if got := cfg.GetHostByName("web2").Registries; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected registries of web2: %v", got)
+	}
+}
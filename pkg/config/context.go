@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of a context
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Context is a named inventory with the defaults used for it
+type Context struct {
+	Name string `yaml:"name"`
+	// Config is the path of the inventory file
+	Config string `yaml:"config"`
+	// Selector limits the hosts of commands given no selector
+	Selector string `yaml:"selector,omitempty"`
+	// Output is the default output format, OutputTable or OutputJSON
+	Output string `yaml:"output,omitempty"`
+}
+
+// Contexts is the contents of the contexts file
+type Contexts struct {
+	// Current is the context used when none is given
+	Current  string    `yaml:"current,omitempty"`
+	Contexts []Context `yaml:"contexts,omitempty"`
+}
+
+// ContextNotFoundError is returned for an unknown context name
+type ContextNotFoundError struct {
+	Name string
+}
+
+func (e *ContextNotFoundError) Error() string {
+	return fmt.Sprintf("context '%s' not found", e.Name)
+}
+
+// ContextsPath returns the path of the contexts file,
+// ~/.config/podman-swarm/contexts.yaml
+func ContextsPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "podman-swarm", "contexts.yaml")
+}
+
+// LoadContexts reads the contexts file. A missing file has no contexts.
+func LoadContexts() (*Contexts, error) {
+	path := ContextsPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Contexts{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contexts: %w", err)
+	}
+
+	var contexts Contexts
+	if err := yaml.Unmarshal(data, &contexts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &contexts, nil
+}
+
+// Save writes the contexts file
+func (c *Contexts) Save() error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return writeFileAtomic(ContextsPath(), buf.Bytes())
+}
+
+// Get returns the named context
+func (c *Contexts) Get(name string) (*Context, error) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			ctx := c.Contexts[i]
+			return &ctx, nil
+		}
+	}
+	return nil, &ContextNotFoundError{Name: name}
+}
+
+// Set adds ctx, or replaces the context with the same name
+func (c *Contexts) Set(ctx Context) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == ctx.Name {
+			c.Contexts[i] = ctx
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, ctx)
+}
+
+// Remove deletes the named context, and makes no context current if it was
+func (c *Contexts) Remove(name string) error {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.Current == name {
+				c.Current = ""
+			}
+			return nil
+		}
+	}
+	return &ContextNotFoundError{Name: name}
+}
+
+// Validate checks the settings of a context
+func (ctx Context) Validate() error {
+	if ctx.Name == "" {
+		return fmt.Errorf("context has no name")
+	}
+	if ctx.Config == "" {
+		return fmt.Errorf("context %s has no config", ctx.Name)
+	}
+	if ctx.Output != "" && ctx.Output != OutputTable && ctx.Output != OutputJSON {
+		return fmt.Errorf("context %s has unknown output format %q (expected %s or %s)", ctx.Name, ctx.Output, OutputTable, OutputJSON)
+	}
+	return nil
+}
+
+var configPathOverride string
+
+// SetConfigPath makes Load read the inventory at path, expanding a leading ~.
+// An empty path restores $PODMAN_SWARM_CONFIG or ~/.config/podman-swarm/hosts.yaml.
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// ConfigPath returns the path of the inventory Load reads
+func ConfigPath() string {
+	return getConfigPath()
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestContexts verifies that contexts are saved and loaded, and that
+// removing the current context leaves none current
+func TestContexts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	contexts, err := LoadContexts()
+	if err != nil {
+		t.Fatalf("LoadContexts should not fail without a file: %v", err)
+	}
+	if len(contexts.Contexts) != 0 {
+		t.Fatalf("expected no contexts, got %+v", contexts)
+	}
+
+	contexts.Set(Context{Name: "staging", Config: "/etc/staging.yaml"})
+	contexts.Set(Context{Name: "prod", Config: "/etc/prod.yaml", Selector: "env=prod", Output: OutputJSON})
+	contexts.Set(Context{Name: "staging", Config: "~/staging.yaml"})
+	contexts.Current = "prod"
+	if err := contexts.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+
+	loaded, err := LoadContexts()
+	if err != nil {
+		t.Fatalf("LoadContexts should not fail: %v", err)
+	}
+	if len(loaded.Contexts) != 2 || loaded.Current != "prod" {
+		t.Fatalf("expected 2 contexts with prod current, got %+v", loaded)
+	}
+	staging, err := loaded.Get("staging")
+	if err != nil || staging.Config != "~/staging.yaml" {
+		t.Errorf("expected staging to be replaced, got %+v, %v", staging, err)
+	}
+	prod, err := loaded.Get("prod")
+	if err != nil || prod.Selector != "env=prod" || prod.Output != OutputJSON {
+		t.Errorf("expected prod to keep its defaults, got %+v, %v", prod, err)
+	}
+
+	if err := loaded.Remove("prod"); err != nil {
+		t.Fatalf("Remove should not fail: %v", err)
+	}
+	if loaded.Current != "" {
+		t.Errorf("expected no current context, got %s", loaded.Current)
+	}
+	var notFound *ContextNotFoundError
+	if _, err := loaded.Get("prod"); !errors.As(err, &notFound) {
+		t.Errorf("expected ContextNotFoundError, got %v", err)
+	}
+}
+
+// TestContext_Validate verifies that unknown output formats are rejected
+func TestContext_Validate(t *testing.T) {
+	if err := (Context{Name: "a", Config: "a.yaml", Output: "yaml"}).Validate(); err == nil {
+		t.Error("expected an error for output yaml")
+	}
+	if err := (Context{Name: "a", Config: "a.yaml", Output: OutputJSON}).Validate(); err != nil {
+		t.Errorf("expected json output to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,604 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Editor modifies the configuration file, keeping its comments and the
+// order of its entries. Only the file itself is edited, not the files it
+// includes.
+type Editor struct {
+	path   string
+	doc    *yaml.Node
+	indent int
+	// blank holds the nodes preceded by a blank line in the file, which the
+	// YAML encoder drops
+	blank map[*yaml.Node]bool
+}
+
+// Edit opens the configuration file at the default path for editing. A
+// missing file is created on Save.
+func Edit() (*Editor, error) {
+	path := getConfigPath()
+	e := &Editor{path: path, indent: 2}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config: %s is not a mapping", path)
+	}
+	e.doc = &doc
+	e.blank = blankLines(&doc, data)
+	if indent := detectIndent(data); indent > 0 {
+		e.indent = indent
+	}
+	return e, nil
+}
+
+// Path returns the path of the edited file
+func (e *Editor) Path() string {
+	return e.path
+}
+
+// detectIndent returns the smallest indentation of the lines of data, or 0
+// if no line is indented
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	return indent
+}
+
+// blankLines returns the nodes of doc that start a line of data preceded by
+// a blank line, not counting the comment lines in between
+func blankLines(doc *yaml.Node, data []byte) map[*yaml.Node]bool {
+	lines := strings.Split(string(data), "\n")
+	blank := map[*yaml.Node]bool{}
+	seen := map[int]bool{}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		// The first node of a line stands for the line, e.g. the key of a
+		// mapping entry or the item of a sequence
+		if n.Line > 1 && !seen[n.Line] {
+			seen[n.Line] = true
+			if i := lineAbove(lines, n.Line); i >= 0 && strings.TrimSpace(lines[i]) == "" {
+				blank[n] = true
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return blank
+}
+
+// lineAbove returns the index in lines of the closest line above line
+// (1-based) that is not a comment, or -1
+func lineAbove(lines []string, line int) int {
+	i := line - 2
+	for i >= 0 && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+		i--
+	}
+	return i
+}
+
+// restoreBlankLines inserts the blank lines of the file back into data, the
+// encoding of doc, before the nodes that had one
+func restoreBlankLines(data []byte, doc *yaml.Node, blank map[*yaml.Node]bool) ([]byte, error) {
+	if len(blank) == 0 {
+		return data, nil
+	}
+	var encoded yaml.Node
+	if err := yaml.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	insert := map[int]bool{}
+	// The encoding has the shape of doc, so nodes are matched by position
+	var walk func(n, out *yaml.Node)
+	walk = func(n, out *yaml.Node) {
+		if blank[n] && out.Line > 1 {
+			if i := lineAbove(lines, out.Line); i >= 0 && strings.TrimSpace(lines[i]) != "" {
+				insert[i+1] = true
+			}
+		}
+		if n.Kind != out.Kind || len(n.Content) != len(out.Content) {
+			return
+		}
+		for i := range n.Content {
+			walk(n.Content[i], out.Content[i])
+		}
+	}
+	walk(doc, &encoded)
+
+	var buf bytes.Buffer
+	for i, line := range lines {
+		if insert[i] {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(line)
+		if i < len(lines)-1 {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Editor) root() *yaml.Node {
+	return e.doc.Content[0]
+}
+
+// sequence returns the sequence at key of a mapping, creating it if create
+// is set
+func sequence(mapping *yaml.Node, key string, create bool) *yaml.Node {
+	if node := mappingValue(mapping, key); node != nil {
+		if node.Kind == yaml.SequenceNode {
+			return node
+		}
+		if !create {
+			return nil
+		}
+		// Replace an empty value such as "hosts:"
+		*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		return node
+	}
+	if !create {
+		return nil
+	}
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, node)
+	return node
+}
+
+// find returns the index of the item of a sequence of mappings with the
+// given name, or -1
+func find(seq *yaml.Node, name string) int {
+	if seq == nil {
+		return -1
+	}
+	for i, item := range seq.Content {
+		if n := mappingValue(item, "name"); n != nil && n.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeKey deletes key from a mapping and reports whether it was set
+func removeKey(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setKey sets key of a mapping to value, keeping its position if it is set
+func setKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	if existing := mappingValue(mapping, key); existing != nil {
+		// Keep comments attached to the old value
+		value.HeadComment, value.LineComment = existing.HeadComment, existing.LineComment
+		*existing = *value
+		return
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeName deletes every scalar equal to name from a sequence, reporting
+// whether there was one
+func removeName(seq *yaml.Node, name string) bool {
+	if seq == nil {
+		return false
+	}
+	removed := false
+	items := seq.Content[:0]
+	for _, item := range seq.Content {
+		if item.Kind == yaml.ScalarNode && item.Value == name {
+			removed = true
+			continue
+		}
+		items = append(items, item)
+	}
+	seq.Content = items
+	return removed
+}
+
+// hostNode returns the mapping of the named host
+func (e *Editor) hostNode(name string) (*yaml.Node, error) {
+	hosts := sequence(e.root(), "hosts", false)
+	i := find(hosts, name)
+	if i < 0 {
+		return nil, &NotFoundError{Name: name}
+	}
+	return hosts.Content[i], nil
+}
+
+// groupNode returns the mapping of the named group
+func (e *Editor) groupNode(name string) (*yaml.Node, error) {
+	groups := sequence(e.root(), "groups", false)
+	i := find(groups, name)
+	if i < 0 {
+		return nil, &NotFoundError{Name: name}
+	}
+	return groups.Content[i], nil
+}
+
+// AddHost appends a host to the inventory
+func (e *Editor) AddHost(host Host) error {
+	if host.Name == "" {
+		return fmt.Errorf("host has no name")
+	}
+	hosts := sequence(e.root(), "hosts", true)
+	if find(hosts, host.Name) >= 0 {
+		return fmt.Errorf("host %s already exists", host.Name)
+	}
+	var node yaml.Node
+	if err := node.Encode(host); err != nil {
+		return err
+	}
+	hosts.Content = append(hosts.Content, &node)
+	return nil
+}
+
+// RemoveHost deletes a host and removes it from the groups. A host still
+// used as a jump host by other hosts, group vars or the defaults is only
+// removed with force, which also drops it from their jump hosts.
+func (e *Editor) RemoveHost(name string, force bool) error {
+	hosts := sequence(e.root(), "hosts", false)
+	i := find(hosts, name)
+	if i < 0 {
+		return &NotFoundError{Name: name}
+	}
+
+	var referrers []string
+	e.jumpSettings(func(owner string, settings *yaml.Node) {
+		if owner != "host "+name && usesJumpHost(settings, name) {
+			referrers = append(referrers, owner)
+		}
+	})
+	if len(referrers) > 0 && !force {
+		return fmt.Errorf("host %s is a jump host of %s; use --force to remove it anyway", name, strings.Join(referrers, ", "))
+	}
+	e.jumpSettings(func(owner string, settings *yaml.Node) {
+		removeJumpHost(settings, name)
+	})
+	hosts.Content = append(hosts.Content[:i], hosts.Content[i+1:]...)
+
+	if groups := sequence(e.root(), "groups", false); groups != nil {
+		for _, g := range groups.Content {
+			removeName(mappingValue(g, "hosts"), name)
+		}
+	}
+	return nil
+}
+
+// jumpSettings calls fn with the settings of the defaults, of the vars of
+// every group and of every host, where jump hosts may be set
+func (e *Editor) jumpSettings(fn func(owner string, settings *yaml.Node)) {
+	if defaults := mappingValue(e.root(), "defaults"); defaults != nil {
+		fn("defaults", defaults)
+	}
+	if groups := sequence(e.root(), "groups", false); groups != nil {
+		for _, g := range groups.Content {
+			if vars := mappingValue(g, "vars"); vars != nil {
+				fn("group "+nodeName(g), vars)
+			}
+		}
+	}
+	if hosts := sequence(e.root(), "hosts", false); hosts != nil {
+		for _, h := range hosts.Content {
+			fn("host "+nodeName(h), h)
+		}
+	}
+}
+
+// nodeName returns the name of a host or group mapping
+func nodeName(mapping *yaml.Node) string {
+	if n := mappingValue(mapping, "name"); n != nil {
+		return n.Value
+	}
+	return ""
+}
+
+// usesJumpHost reports whether the jump hosts of settings refer to the
+// named inventory host
+func usesJumpHost(settings *yaml.Node, name string) bool {
+	jump := mappingValue(settings, "jump")
+	if jump == nil {
+		return false
+	}
+	if jump.Kind == yaml.ScalarNode {
+		for _, hop := range strings.Split(jump.Value, ",") {
+			if strings.TrimSpace(hop) == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, hop := range jump.Content {
+		if isJumpRef(hop, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeJumpHost drops the hops referring to the named inventory host from
+// the jump hosts of settings, and the jump setting if none are left
+func removeJumpHost(settings *yaml.Node, name string) {
+	jump := mappingValue(settings, "jump")
+	if jump == nil {
+		return
+	}
+	switch jump.Kind {
+	case yaml.ScalarNode:
+		var hops []string
+		for _, hop := range strings.Split(jump.Value, ",") {
+			if strings.TrimSpace(hop) != name {
+				hops = append(hops, hop)
+			}
+		}
+		jump.Value = strings.Join(hops, ",")
+		if len(hops) == 0 {
+			removeKey(settings, "jump")
+		}
+	case yaml.SequenceNode:
+		hops := jump.Content[:0]
+		for _, hop := range jump.Content {
+			if !isJumpRef(hop, name) {
+				hops = append(hops, hop)
+			}
+		}
+		jump.Content = hops
+		if len(hops) == 0 {
+			removeKey(settings, "jump")
+		}
+	}
+}
+
+// isJumpRef reports whether a jump hop, written as a name or with "host:",
+// refers to the named inventory host
+func isJumpRef(hop *yaml.Node, name string) bool {
+	if hop.Kind == yaml.ScalarNode {
+		return hop.Value == name
+	}
+	ref := mappingValue(hop, "host")
+	return ref != nil && ref.Value == name
+}
+
+// SetHost sets a setting of a host, such as "port", "labels.env" or
+// "registries.quay.io". The value is parsed as YAML, so "[bastion]" sets a
+// list. Renaming a host updates the groups referring to it.
+func (e *Editor) SetHost(name, key, value string) error {
+	host, err := e.hostNode(name)
+	if err != nil {
+		return err
+	}
+	if setting, entry, ok := mapSettingKey(key); ok {
+		values := mappingValue(host, setting)
+		if values == nil || values.Kind != yaml.MappingNode {
+			values = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setKey(host, setting, values)
+		}
+		// Labels and credentials are strings, even when they look like
+		// numbers
+		setKey(values, entry, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		return nil
+	}
+	if err := checkHostKey(key); err != nil {
+		return err
+	}
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""}
+	if len(parsed.Content) > 0 {
+		node = parsed.Content[0]
+	}
+	if key == "name" {
+		if value == "" {
+			return fmt.Errorf("host name cannot be empty")
+		}
+		if _, err := e.hostNode(value); err == nil {
+			return fmt.Errorf("host %s already exists", value)
+		}
+		e.renameHost(name, value)
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	}
+	setKey(host, key, node)
+	return nil
+}
+
+// UnsetHost deletes a setting of a host, such as "port" or "labels.env"
+func (e *Editor) UnsetHost(name, key string) error {
+	host, err := e.hostNode(name)
+	if err != nil {
+		return err
+	}
+	if setting, entry, ok := mapSettingKey(key); ok {
+		if values := mappingValue(host, setting); values == nil || !removeKey(values, entry) {
+			return fmt.Errorf("host %s does not set %s", name, key)
+		}
+		return nil
+	}
+	if err := checkHostKey(key); err != nil {
+		return err
+	}
+	if key == "name" {
+		return fmt.Errorf("cannot unset the name of host %s", name)
+	}
+	if !removeKey(host, key) {
+		return fmt.Errorf("host %s does not set %s", name, key)
+	}
+	return nil
+}
+
+// mapSettingKey splits a key like "labels.env" into a setting inherited key
+// by key and the key of its entry
+func mapSettingKey(key string) (string, string, bool) {
+	for _, setting := range mapSettings {
+		if entry, ok := strings.CutPrefix(key, setting+"."); ok && entry != "" {
+			return setting, entry, true
+		}
+	}
+	return "", "", false
+}
+
+// checkHostKey returns an error if key is not a host setting
+func checkHostKey(key string) error {
+	if _, ok := structFields(reflect.TypeOf(Host{}))[key]; !ok {
+		return fmt.Errorf("unknown host setting %q", key)
+	}
+	return nil
+}
+
+// renameHost updates the group members and jump hops referring to a host
+func (e *Editor) renameHost(from, to string) {
+	rename := func(seq *yaml.Node) {
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, item := range seq.Content {
+			if item.Kind == yaml.ScalarNode && item.Value == from {
+				item.Value = to
+			}
+		}
+	}
+	if groups := sequence(e.root(), "groups", false); groups != nil {
+		for _, g := range groups.Content {
+			rename(mappingValue(g, "hosts"))
+		}
+	}
+	if hosts := sequence(e.root(), "hosts", false); hosts != nil {
+		for _, h := range hosts.Content {
+			rename(mappingValue(h, "jump"))
+		}
+	}
+}
+
+// AddGroup appends a group to the inventory
+func (e *Editor) AddGroup(group HostGroup) error {
+	if group.Name == "" {
+		return fmt.Errorf("group has no name")
+	}
+	groups := sequence(e.root(), "groups", true)
+	if find(groups, group.Name) >= 0 {
+		return fmt.Errorf("group %s already exists", group.Name)
+	}
+	var node yaml.Node
+	if err := node.Encode(group); err != nil {
+		return err
+	}
+	groups.Content = append(groups.Content, &node)
+	return nil
+}
+
+// RemoveGroup deletes a group and removes it from the children of other
+// groups
+func (e *Editor) RemoveGroup(name string) error {
+	groups := sequence(e.root(), "groups", false)
+	i := find(groups, name)
+	if i < 0 {
+		return &NotFoundError{Name: name}
+	}
+	groups.Content = append(groups.Content[:i], groups.Content[i+1:]...)
+	for _, g := range groups.Content {
+		removeName(mappingValue(g, "children"), name)
+	}
+	return nil
+}
+
+// AddGroupHost adds a host to a group. Adding a member again does nothing.
+func (e *Editor) AddGroupHost(group, host string) error {
+	g, err := e.groupNode(group)
+	if err != nil {
+		return err
+	}
+	hosts := sequence(g, "hosts", true)
+	for _, item := range hosts.Content {
+		if item.Value == host {
+			return nil
+		}
+	}
+	hosts.Content = append(hosts.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: host})
+	return nil
+}
+
+// RemoveGroupHost removes a host from a group
+func (e *Editor) RemoveGroupHost(group, host string) error {
+	g, err := e.groupNode(group)
+	if err != nil {
+		return err
+	}
+	if !removeName(mappingValue(g, "hosts"), host) {
+		return fmt.Errorf("host %s is not in group %s", host, group)
+	}
+	return nil
+}
+
+func (e *Editor) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(e.indent)
+	if err := encoder.Encode(e.doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return restoreBlankLines(buf.Bytes(), e.doc, e.blank)
+}
+
+// Validate loads the edited configuration as Load would, without saving it
+func (e *Editor) Validate() (*Config, error) {
+	data, err := e.encode()
+	if err != nil {
+		return nil, err
+	}
+	cfg, problems, err := loadData(e.path, data)
+	if err != nil {
+		return nil, err
+	}
+	return resolve(cfg, problems)
+}
+
+// Save validates the edited configuration and writes it. Nothing is written
+// if it is invalid.
+func (e *Editor) Save() error {
+	if _, err := e.Validate(); err != nil {
+		return err
+	}
+	data, err := e.encode()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(e.path, data)
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEditor verifies that edits keep comments and order and update the
+// references to renamed and removed hosts
+func TestEditor(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `# Fleet inventory
+hosts:
+  # the bastion
+  - name: bastion
+    address: 10.0.0.1 # public
+  - name: web1
+    address: 10.0.1.1
+    jump: [bastion]
+  - name: db1
+    address: 10.0.2.1
+groups:
+  - name: web
+    hosts: [web1, db1] # web servers
+  - name: all-servers
+    children: [web]
+`,
+	})
+
+	editor, err := Edit()
+	if err != nil {
+		t.Fatalf("Edit should not fail: %v", err)
+	}
+	steps := []func() error{
+		func() error {
+			return editor.AddHost(Host{Name: "web2", Address: "10.0.1.2", Labels: map[string]string{"env": "prod"}})
+		},
+		func() error { return editor.AddGroupHost("web", "web2") },
+		func() error { return editor.RemoveGroupHost("web", "db1") },
+		func() error { return editor.SetHost("web2", "port", "2222") },
+		func() error { return editor.SetHost("web2", "labels.zone", "1") },
+		func() error { return editor.SetHost("bastion", "name", "gw") },
+		func() error { return editor.RemoveHost("db1", false) },
+		func() error { return editor.AddGroup(HostGroup{Name: "db"}) },
+		func() error { return editor.RemoveGroup("web") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d should not fail: %v", i, err)
+		}
+	}
+	if err := editor.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Fleet inventory
+hosts:
+  # the bastion
+  - name: gw
+    address: 10.0.0.1 # public
+  - name: web1
+    address: 10.0.1.1
+    jump: [gw]
+  - name: web2
+    address: 10.0.1.2
+    labels:
+      env: prod
+      zone: "1"
+    port: 2222
+groups:
+  - name: all-servers
+    children: []
+  - name: db
+`
+	if string(data) != expected {
+		t.Errorf("unexpected file contents:\n%s", data)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+	if h := cfg.GetHostByName("web2"); h == nil || h.Port != 2222 || h.Labels["zone"] != "1" {
+		t.Errorf("expected web2 with its settings, got %+v", h)
+	}
+}
+
+// TestEditor_Invalid verifies that an invalid inventory is not saved
+func TestEditor_Invalid(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `hosts:
+  - name: bastion
+    address: 10.0.0.1
+  - name: web1
+    address: 10.0.1.1
+    jump: [bastion]
+`,
+	})
+
+	editor, err := Edit()
+	if err != nil {
+		t.Fatalf("Edit should not fail: %v", err)
+	}
+	if err := editor.SetHost("web1", "port", "70000"); err != nil {
+		t.Fatalf("SetHost should not fail: %v", err)
+	}
+	if err := editor.Save(); err == nil || !strings.Contains(err.Error(), "invalid port 70000") {
+		t.Errorf("expected an invalid port error, got: %v", err)
+	}
+
+	if err := editor.SetHost("web1", "colour", "blue"); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+	if err := editor.RemoveHost("nonexistent", false); err == nil {
+		t.Error("expected an error for an unknown host")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "hosts.yaml"))
+	if strings.Contains(string(data), "70000") {
+		t.Errorf("invalid inventory should not be saved:\n%s", data)
+	}
+}
+
+// TestEditor_BlankLines verifies that blank lines separating entries are
+// kept
+func TestEditor_BlankLines(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `defaults:
+  username: deploy
+
+hosts:
+  - name: web1
+    address: 10.0.1.1
+
+  # database
+  - name: db1
+    address: 10.0.2.1
+
+groups:
+  - name: web
+    hosts: [web1]
+`,
+	})
+
+	editor, err := Edit()
+	if err != nil {
+		t.Fatalf("Edit should not fail: %v", err)
+	}
+	if err := editor.SetHost("web1", "port", "2222"); err != nil {
+		t.Fatalf("SetHost should not fail: %v", err)
+	}
+	if err := editor.AddHost(Host{Name: "web2", Address: "10.0.1.2"}); err != nil {
+		t.Fatalf("AddHost should not fail: %v", err)
+	}
+	if err := editor.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `defaults:
+  username: deploy
+
+hosts:
+  - name: web1
+    address: 10.0.1.1
+    port: 2222
+
+  # database
+  - name: db1
+    address: 10.0.2.1
+  - name: web2
+    address: 10.0.1.2
+
+groups:
+  - name: web
+    hosts: [web1]
+`
+	if string(data) != expected {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+}
+
+// TestEditor_RemoveJumpHost verifies that a host used as a jump host is
+// only removed with force, which drops it from the jump hosts
+func TestEditor_RemoveJumpHost(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `hosts:
+  - name: bastion
+    address: 10.0.0.1
+  - name: gw
+    address: 10.0.0.2
+  - name: web1
+    address: 10.0.1.1
+    jump: [gw, bastion]
+groups:
+  - name: web
+    hosts: [web1]
+    vars:
+      jump:
+        - host: bastion
+`,
+	})
+
+	editor, err := Edit()
+	if err != nil {
+		t.Fatalf("Edit should not fail: %v", err)
+	}
+	err = editor.RemoveHost("bastion", false)
+	if err == nil || !strings.Contains(err.Error(), "group web, host web1") {
+		t.Fatalf("expected the referrers to be reported, got: %v", err)
+	}
+	if err := editor.RemoveHost("bastion", true); err != nil {
+		t.Fatalf("RemoveHost with force should not fail: %v", err)
+	}
+	if err := editor.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `hosts:
+  - name: gw
+    address: 10.0.0.2
+  - name: web1
+    address: 10.0.1.1
+    jump: [gw]
+groups:
+  - name: web
+    hosts: [web1]
+    vars: {}
+`
+	if string(data) != expected {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+}
+
+// TestEditor_NewFile verifies that a missing inventory is created
+func TestEditor_NewFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PODMAN_SWARM_CONFIG", filepath.Join(dir, "hosts.yaml"))
+
+	editor, err := Edit()
+	if err != nil {
+		t.Fatalf("Edit should not fail: %v", err)
+	}
+	if err := editor.AddHost(Host{Name: "web1", Address: "10.0.1.1"}); err != nil {
+		t.Fatalf("AddHost should not fail: %v", err)
+	}
+	if err := editor.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+	if _, err := Load(); err != nil {
+		t.Errorf("Load should not fail: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+// AllGroup is the implicit group of every host. A group of the same name in
+// the inventory takes precedence.
+const AllGroup = "all"
+
+// group returns the inventory group with the given name
+func (c *Config) group(name string) *HostGroup {
+	for i := range c.Groups {
+		if c.Groups[i].Name == name {
+			return &c.Groups[i]
+		}
+	}
+	return nil
+}
+
+// groupCycles returns the cycles among group children, each as the path
+// from a group back to itself
+func (c *Config) groupCycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var cycles [][]string
+
+	var visit func(g *HostGroup, path []string)
+	visit = func(g *HostGroup, path []string) {
+		state[g.Name] = visiting
+		path = append(path, g.Name)
+		for _, name := range g.Children {
+			child := c.group(name)
+			if child == nil {
+				continue
+			}
+			switch state[name] {
+			case visiting:
+				for i, p := range path {
+					if p == name {
+						cycles = append(cycles, append(append([]string{}, path[i:]...), name))
+						break
+					}
+				}
+			case unvisited:
+				visit(child, path)
+			}
+		}
+		state[g.Name] = done
+	}
+
+	for i := range c.Groups {
+		if state[c.Groups[i].Name] == unvisited {
+			visit(&c.Groups[i], nil)
+		}
+	}
+	return cycles
+}
+
+// groupHostNames appends the host names of g and its children to names,
+// skipping names already added and groups already visited
+func (c *Config) groupHostNames(g *HostGroup, names []string, added, visited map[string]bool) []string {
+	if visited[g.Name] {
+		return names
+	}
+	visited[g.Name] = true
+
+	for _, name := range g.Hosts {
+		if !added[name] {
+			added[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range g.Children {
+		child := c.group(name)
+		if child == nil {
+			if name == AllGroup {
+				for _, h := range c.Hosts {
+					if !added[h.Name] {
+						added[h.Name] = true
+						names = append(names, h.Name)
+					}
+				}
+			}
+			continue
+		}
+		names = c.groupHostNames(child, names, added, visited)
+	}
+	return names
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DynamicInventory is an executable printing an inventory as JSON, with the
+// same keys as hosts.yaml
+type DynamicInventory struct {
+	// Command is run with sh -c in the directory of the configuration file
+	Command string `mapstructure:"command" yaml:"command"`
+	// CacheTTL is how long the output is reused before running Command
+	// again; 0 runs it on every load
+	CacheTTL time.Duration `mapstructure:"cache_ttl" yaml:"cache_ttl,omitempty"`
+}
+
+// document is a parsed inventory source
+type document struct {
+	file string
+	root *yaml.Node
+}
+
+// entry locates a host or group in the sequence of a document
+type entry struct {
+	doc   int
+	index int
+}
+
+// sources tells which document each host and group of the merged settings
+// comes from
+type sources struct {
+	docs   []document
+	hosts  []entry
+	groups []entry
+}
+
+// newSources returns the sources of the settings of the main document
+func newSources(main document, settings map[string]interface{}) *sources {
+	s := &sources{docs: []document{main}}
+	hosts, _ := settings["hosts"].([]interface{})
+	for i := range hosts {
+		s.hosts = append(s.hosts, entry{0, i})
+	}
+	groups, _ := settings["groups"].([]interface{})
+	for i := range groups {
+		s.groups = append(s.groups, entry{0, i})
+	}
+	return s
+}
+
+// mainOnlyKeys can only be set in the main configuration file
+var mainOnlyKeys = []string{"include", "dynamic", "ssh_config_file"}
+
+// merge adds the settings of another document to settings. Hosts are
+// appended. A group defined by an earlier document gets the hosts and
+// children of the new definition, and its vars are overridden key by key,
+// as are defaults.
+func (s *sources) merge(settings map[string]interface{}, doc document, other map[string]interface{}) error {
+	for _, key := range mainOnlyKeys {
+		if _, ok := other[key]; ok {
+			return fmt.Errorf("%s: %s can only be set in the main configuration file", doc.file, key)
+		}
+	}
+	s.docs = append(s.docs, doc)
+	n := len(s.docs) - 1
+
+	if defaults, ok := other["defaults"].(map[string]interface{}); ok {
+		existing, ok := settings["defaults"].(map[string]interface{})
+		if !ok {
+			existing = map[string]interface{}{}
+			settings["defaults"] = existing
+		}
+		mergeVars(existing, defaults)
+	}
+
+	hosts, _ := settings["hosts"].([]interface{})
+	otherHosts, _ := other["hosts"].([]interface{})
+	for i, h := range otherHosts {
+		hosts = append(hosts, h)
+		s.hosts = append(s.hosts, entry{n, i})
+	}
+	settings["hosts"] = hosts
+
+	groups, _ := settings["groups"].([]interface{})
+	earlier := map[string]map[string]interface{}{}
+	for _, raw := range groups {
+		if g, ok := raw.(map[string]interface{}); ok {
+			if name, ok := g["name"].(string); ok && earlier[name] == nil {
+				earlier[name] = g
+			}
+		}
+	}
+	otherGroups, _ := other["groups"].([]interface{})
+	for i, raw := range otherGroups {
+		g, _ := raw.(map[string]interface{})
+		name, _ := g["name"].(string)
+		if existing := earlier[name]; name != "" && existing != nil {
+			for _, key := range []string{"hosts", "children"} {
+				if _, ok := g[key]; ok {
+					existing[key] = append(rawList(existing[key]), rawList(g[key])...)
+				}
+			}
+			if vars, ok := g["vars"].(map[string]interface{}); ok {
+				existingVars, ok := existing["vars"].(map[string]interface{})
+				if !ok {
+					existingVars = map[string]interface{}{}
+					existing["vars"] = existingVars
+				}
+				mergeVars(existingVars, vars)
+			}
+			continue
+		}
+		groups = append(groups, raw)
+		s.groups = append(s.groups, entry{n, i})
+	}
+	settings["groups"] = groups
+	return nil
+}
+
+// mergeVars sets the settings of src in dst, merging labels and registries
+func mergeVars(dst, src map[string]interface{}) {
+	for key, value := range src {
+		values, ok := value.(map[string]interface{})
+		existing, ok2 := dst[key].(map[string]interface{})
+		if (key == "labels" || key == "registries") && ok && ok2 {
+			for k, v := range values {
+				existing[k] = v
+			}
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// rawList returns a raw list setting, which may also be a comma separated
+// string
+func rawList(value interface{}) []interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		return value
+	case string:
+		var list []interface{}
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+		return list
+	}
+	return nil
+}
+
+// includeFiles returns the files matching the include globs in order,
+// without duplicates. Relative globs are relative to dir.
+func includeFiles(patterns []string, dir, mainFile string) ([]string, error) {
+	seen := map[string]bool{filepath.Clean(mainFile): true}
+	var files []string
+	for _, pattern := range patterns {
+		pattern = expandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err != nil || info.IsDir() {
+				continue
+			}
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	return files, nil
+}
+
+// readInventoryFile reads the settings and document of an included file
+func readInventoryFile(file string) (map[string]interface{}, document, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, document{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, document{}, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	settings, err := inventorySettings(root)
+	if err != nil {
+		return nil, document{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return settings, document{file: file, root: root}, nil
+}
+
+// read returns the settings and document printed by the dynamic inventory,
+// from the cache while it is fresh
+func (d DynamicInventory) read(dir string) (map[string]interface{}, document, error) {
+	name := fmt.Sprintf("output of %q", d.Command)
+	cacheFile := d.cacheFile(dir)
+
+	var data []byte
+	if d.CacheTTL > 0 && cacheFile != "" {
+		if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < d.CacheTTL {
+			data, _ = os.ReadFile(cacheFile)
+		}
+	}
+	fresh := data == nil
+	if fresh {
+		var err error
+		if data, err = d.run(dir); err != nil {
+			return nil, document{}, fmt.Errorf("dynamic inventory %q failed: %w", d.Command, err)
+		}
+	}
+
+	if !json.Valid(data) {
+		return nil, document{}, fmt.Errorf("dynamic inventory %q printed invalid JSON", d.Command)
+	}
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, document{}, fmt.Errorf("dynamic inventory %q printed invalid JSON: %w", d.Command, err)
+	}
+	settings, err := inventorySettings(root)
+	if err != nil {
+		return nil, document{}, fmt.Errorf("dynamic inventory %q printed invalid JSON: %w", d.Command, err)
+	}
+
+	if fresh && d.CacheTTL > 0 && cacheFile != "" {
+		// A cache that cannot be written only costs another run
+		_ = writeFileAtomic(cacheFile, data)
+	}
+	return settings, document{file: name, root: root}, nil
+}
+
+func (d DynamicInventory) run(dir string) ([]byte, error) {
+	if strings.TrimSpace(d.Command) == "" {
+		return nil, fmt.Errorf("no command")
+	}
+	cmd := exec.Command("sh", "-c", d.Command)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// cacheFile returns where the output of the dynamic inventory is cached, or
+// "" if there is no cache directory
+func (d DynamicInventory) cacheFile(dir string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(dir + "\x00" + d.Command))
+	return filepath.Join(cacheDir, "podman-swarm", "inventory", hex.EncodeToString(sum[:])+".json")
+}
+
+// writeFileAtomic replaces file with data, creating its directory
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// mergeSources merges the included files and dynamic inventories of cfg,
+// decoded from settings, into settings
+func (c *Config) mergeSources(settings map[string]interface{}, s *sources, configPath string) error {
+	dir := filepath.Dir(configPath)
+	files, err := includeFiles(c.Include, dir, configPath)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		other, doc, err := readInventoryFile(file)
+		if err != nil {
+			return err
+		}
+		if err := s.merge(settings, doc, other); err != nil {
+			return err
+		}
+	}
+	for _, d := range c.Dynamic {
+		other, doc, err := d.read(dir)
+		if err != nil {
+			return err
+		}
+		if err := s.merge(settings, doc, other); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFiles writes files relative to a temporary directory and points
+// PODMAN_SWARM_CONFIG at its hosts.yaml
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0700); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	t.Setenv("PODMAN_SWARM_CONFIG", filepath.Join(dir, "hosts.yaml"))
+	return dir
+}
+
+// TestLoad_Include verifies that included files are merged in order and
+// that groups defined in several files are merged
+func TestLoad_Include(t *testing.T) {
+	writeTestFiles(t, map[string]string{
+		"hosts.yaml": `
+include: [hosts.d/*.yaml]
+defaults:
+  username: ops
+hosts:
+  - name: bastion
+    address: 10.0.0.1
+groups:
+  - name: web
+    hosts: [web1]
+    vars:
+      labels: {tier: web}
+`,
+		"hosts.d/10-web.yaml": `
+hosts:
+  - name: web1
+    address: 10.0.1.1
+`,
+		"hosts.d/20-web.yaml": `
+defaults:
+  port: 2222
+hosts:
+  - name: web2
+    address: 10.0.1.2
+groups:
+  - name: web
+    hosts: [web2]
+    vars:
+      username: deploy
+`,
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	var names []string
+	for _, h := range cfg.AllHosts() {
+		names = append(names, h.Name)
+	}
+	if strings.Join(names, ",") != "bastion,web1,web2" {
+		t.Errorf("expected hosts in include order, got %v", names)
+	}
+
+	hosts := cfg.GetHostsByGroup("web")
+	if len(hosts) != 2 {
+		t.Fatalf("expected the web group to have 2 hosts, got %d", len(hosts))
+	}
+	for _, h := range hosts {
+		if h.Username != "deploy" || h.Port != 2222 || h.Labels["tier"] != "web" {
+			t.Errorf("expected %s to get merged vars and defaults, got %+v", h.Name, h)
+		}
+	}
+}
+
+// TestValidate_Include verifies that problems in included files are
+// reported at their location
+func TestValidate_Include(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `include: [hosts.d/*.yaml]
+hosts:
+  - name: web1
+    address: 10.0.1.1
+`,
+		"hosts.d/web.yaml": `hosts:
+  - name: web2
+  - name: web1
+    address: 10.0.1.3
+`,
+	})
+
+	problems, err := Validate()
+	if err != nil {
+		t.Fatalf("Validate should not fail: %v", err)
+	}
+	included := filepath.Join(dir, "hosts.d", "web.yaml")
+	expected := []string{
+		included + ":2: error: host web2 has no address",
+		included + ":3: error: host web1 is defined more than once (first at " + filepath.Join(dir, "hosts.yaml") + ":3)",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), problems)
+	}
+	for i, want := range expected {
+		if problems[i].String() != want {
+			t.Errorf("problem %d: expected %q, got %q", i, want, problems[i].String())
+		}
+	}
+}
+
+// TestLoad_IncludeMainOnlyKeys verifies that included files cannot include
+// other inventories
+func TestLoad_IncludeMainOnlyKeys(t *testing.T) {
+	writeTestFiles(t, map[string]string{
+		"hosts.yaml": "include: [more.yaml]\n",
+		"more.yaml":  "include: [hosts.yaml]\n",
+	})
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "include can only be set in the main configuration file") {
+		t.Errorf("expected an error about include, got: %v", err)
+	}
+}
+
+// TestLoad_Dynamic verifies that the output of a dynamic inventory is merged
+// and cached for its TTL
+func TestLoad_Dynamic(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := writeTestFiles(t, map[string]string{
+		"hosts.yaml": `
+dynamic:
+  - command: ./inventory.sh
+    cache_ttl: 1h
+hosts:
+  - name: bastion
+    address: 10.0.0.1
+`,
+		"inventory.sh": `#!/bin/sh
+echo run >> runs
+cat <<'EOF'
+{"hosts": [{"name": "vm1", "address": "10.0.2.1", "labels": {"env": "dev"}}],
+ "groups": [{"name": "vms", "hosts": ["vm1"], "vars": {"jump": ["bastion"]}}]}
+EOF
+`,
+	})
+
+	for i := 0; i < 2; i++ {
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load should not fail: %v", err)
+		}
+		hosts := cfg.GetHostsByGroup("vms")
+		if len(hosts) != 1 || hosts[0].Address != "10.0.2.1" || hosts[0].Labels["env"] != "dev" {
+			t.Fatalf("expected vm1 from the dynamic inventory, got %+v", hosts)
+		}
+		if len(hosts[0].Jump) != 1 || hosts[0].Jump[0].Address != "10.0.0.1" {
+			t.Errorf("expected vm1 to jump through bastion, got %+v", hosts[0].Jump)
+		}
+	}
+
+	runs, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatalf("inventory was not run: %v", err)
+	}
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("expected the cached output to be reused, inventory ran %d times", n)
+	}
+}
+
+// TestLoad_DynamicFailure verifies that a failing dynamic inventory fails
+// loading with its error output
+func TestLoad_DynamicFailure(t *testing.T) {
+	writeTestFiles(t, map[string]string{
+		"hosts.yaml": `
+dynamic:
+  - command: echo 'cloud API unreachable' >&2; exit 1
+`,
+	})
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "cloud API unreachable") {
+		t.Errorf("expected the inventory's error output, got: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JumpHost is a bastion hop on the way to a host. It either refers to an
+// inventory host by name, whose connection settings and own jump chain are
+// used, or describes the connection inline. Inline hops inherit unset
+// credentials from the target host.
+//
+// In YAML a hop may also be written as a string: an inventory host name or
+// "[user@]address[:port]".
+type JumpHost struct {
+	Ref  string `mapstructure:"host" yaml:"host,omitempty"`
+	Host `mapstructure:",squash" yaml:",inline"`
+}
+
+// String formats the hop as "[user@]address[:port]"
+func (h JumpHost) String() string {
+	s := h.Address
+	if strings.Contains(s, ":") {
+		s = "[" + s + "]"
+	}
+	if h.Port != 0 {
+		s = fmt.Sprintf("%s:%d", s, h.Port)
+	}
+	if h.Username != "" {
+		s = h.Username + "@" + s
+	}
+	return s
+}
+
+// jumpHostHook decodes the string form of a jump hop
+func jumpHostHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(JumpHost{}) {
+		return data, nil
+	}
+	return parseJumpSpec(data.(string))
+}
+
+func parseJumpSpec(spec string) (JumpHost, error) {
+	if !strings.ContainsAny(spec, "@:") {
+		return JumpHost{Ref: spec}, nil
+	}
+
+	var hop JumpHost
+	rest := spec
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		hop.Username = rest[:i]
+		rest = rest[i+1:]
+	}
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.HasSuffix(rest, "]") {
+		port, err := strconv.Atoi(rest[i+1:])
+		if err != nil {
+			return JumpHost{}, fmt.Errorf("invalid port in jump host %q", spec)
+		}
+		hop.Port = port
+		rest = rest[:i]
+	}
+	hop.Address = strings.TrimSuffix(strings.TrimPrefix(rest, "["), "]")
+	if hop.Address == "" {
+		return JumpHost{}, fmt.Errorf("missing address in jump host %q", spec)
+	}
+	return hop, nil
+}
+
+// resolveJumps flattens every host's jump chain into fully specified hops,
+// expanding references to other inventory hosts
+func (c *Config) resolveJumps() error {
+	chains := make([][]JumpHost, len(c.Hosts))
+	for i := range c.Hosts {
+		chain, err := c.jumpChain(c.Hosts[i], []string{c.Hosts[i].Name})
+		if err != nil {
+			return err
+		}
+		chains[i] = chain
+	}
+	for i := range c.Hosts {
+		c.Hosts[i].Jump = chains[i]
+	}
+	return nil
+}
+
+func (c *Config) jumpChain(target Host, path []string) ([]JumpHost, error) {
+	var chain []JumpHost
+	for _, hop := range target.Jump {
+		if hop.Ref != "" {
+			ref := c.GetHostByName(hop.Ref)
+			if ref == nil {
+				// Not an inventory host: treat the name as an address
+				hop.Address = hop.Ref
+				hop.Ref = ""
+			} else {
+				for _, name := range path {
+					if name == ref.Name {
+						return nil, fmt.Errorf("jump host cycle: %s -> %s", strings.Join(path, " -> "), ref.Name)
+					}
+				}
+				refChain, err := c.jumpChain(*ref, append(append([]string{}, path...), ref.Name))
+				if err != nil {
+					return nil, err
+				}
+				chain = append(chain, refChain...)
+
+				resolved := *ref
+				resolved.Jump = nil
+				chain = append(chain, JumpHost{Ref: hop.Ref, Host: resolved})
+				continue
+			}
+		}
+
+		hop.Jump = nil
+		if hop.Username == "" {
+			hop.Username = target.Username
+		}
+		if hop.PrivateKey == "" {
+			hop.PrivateKey = target.PrivateKey
+		}
+		if hop.KnownHosts == "" {
+			hop.KnownHosts = target.KnownHosts
+		}
+		if hop.Certificate == "" && hop.PrivateKey == target.PrivateKey {
+			hop.Certificate = target.Certificate
+		}
+		if hop.PassphraseEnv == "" && hop.PassphraseCommand == "" && hop.Passphrase == "" && hop.PrivateKey == target.PrivateKey {
+			hop.PassphraseEnv = target.PassphraseEnv
+			hop.PassphraseCommand = target.PassphraseCommand
+			hop.Passphrase = target.Passphrase
+		}
+		if len(hop.AuthOrder) == 0 {
+			hop.AuthOrder = target.AuthOrder
+		}
+		if !hop.TrustOnFirstUse {
+			hop.TrustOnFirstUse = target.TrustOnFirstUse
+		}
+		hop.PrivateKey = expandPath(hop.PrivateKey)
+		hop.KnownHosts = expandPath(hop.KnownHosts)
+		hop.Certificate = expandPath(hop.Certificate)
+		if hop.Name == "" {
+			hop.Name = hop.Address
+		}
+		chain = append(chain, hop)
+	}
+	return chain, nil
+}
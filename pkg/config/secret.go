@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prefixes of secret references
+const (
+	SecretStorePrefix   = "secret://"
+	SecretEnvPrefix     = "env://"
+	SecretCommandPrefix = "cmd://"
+)
+
+const redacted = "[redacted]"
+
+// Secret is a sensitive setting. It holds either the value itself or a
+// reference resolved when the value is needed: "secret://name" for an entry
+// of the encrypted secret store, "env://VAR" for an environment variable or
+// "cmd://command" for the output of a shell command. Printing or encoding a
+// Secret as JSON shows references but never values.
+type Secret string
+
+// IsReference reports whether s refers to a value kept elsewhere
+func (s Secret) IsReference() bool {
+	v := string(s)
+	return strings.HasPrefix(v, SecretStorePrefix) || strings.HasPrefix(v, SecretEnvPrefix) || strings.HasPrefix(v, SecretCommandPrefix)
+}
+
+func (s Secret) String() string {
+	if s == "" || s.IsReference() {
+		return string(s)
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Resolve returns the value of the secret. The secret store is opened on
+// first use, asking for its passphrase if needed.
+func (s Secret) Resolve() (string, error) {
+	v := string(s)
+	switch {
+	case strings.HasPrefix(v, SecretStorePrefix):
+		name := strings.TrimPrefix(v, SecretStorePrefix)
+		store, err := sharedSecretStore()
+		if err != nil {
+			return "", err
+		}
+		return store.Get(name)
+	case strings.HasPrefix(v, SecretEnvPrefix):
+		name := strings.TrimPrefix(v, SecretEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("$%s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(v, SecretCommandPrefix):
+		command := strings.TrimPrefix(v, SecretCommandPrefix)
+		var stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%s failed: %w: %s", command, err, msg)
+			}
+			return "", fmt.Errorf("%s failed: %w", command, err)
+		}
+		return string(bytes.TrimRight(out, "\r\n")), nil
+	}
+	return v, nil
+}
+
+var (
+	secretStoreOnce sync.Once
+	secretStore     *SecretStore
+	secretStoreErr  error
+)
+
+// sharedSecretStore opens the secret store once per process
+func sharedSecretStore() (*SecretStore, error) {
+	secretStoreOnce.Do(func() {
+		secretStore, secretStoreErr = OpenSecretStore(false)
+	})
+	return secretStore, secretStoreErr
+}
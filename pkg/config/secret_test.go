@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSecret_Redacted verifies that secret values are never printed, while
+// references are
+func TestSecret_Redacted(t *testing.T) {
+	host := Host{Name: "web1", Passphrase: "hunter2", Registries: map[string]Secret{"quay.io": "secret://quay"}}
+
+	for _, out := range []string{fmt.Sprint(host.Passphrase), fmt.Sprintf("%+v", host), fmt.Sprintf("%#v", host)} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("secret value printed: %s", out)
+		}
+	}
+	data, err := json.Marshal(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), "secret://quay") {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
+
+// TestSecret_Resolve verifies that env:// and cmd:// references and literal
+// values are resolved
+func TestSecret_Resolve(t *testing.T) {
+	t.Setenv("TEST_SECRET", "from-env")
+
+	tests := []struct {
+		secret   Secret
+		expected string
+	}{
+		{"literal", "literal"},
+		{"env://TEST_SECRET", "from-env"},
+		{"cmd://echo from-cmd", "from-cmd"},
+	}
+	for _, tc := range tests {
+		value, err := tc.secret.Resolve()
+		if err != nil || value != tc.expected {
+			t.Errorf("%s: expected %q, got %q, %v", tc.secret, tc.expected, value, err)
+		}
+	}
+
+	if _, err := Secret("env://TEST_SECRET_UNSET").Resolve(); err == nil {
+		t.Error("expected an error for an unset variable")
+	}
+	if _, err := Secret("cmd://exit 1").Resolve(); err == nil {
+		t.Error("expected an error for a failing command")
+	}
+}
+
+// TestSecretStore verifies that secrets are saved encrypted and can only be
+// read with the passphrase
+func TestSecretStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(SecretPassphraseEnv, "correct horse")
+
+	if _, err := OpenSecretStore(false); err == nil {
+		t.Error("expected an error without a store")
+	}
+
+	store, err := OpenSecretStore(true)
+	if err != nil {
+		t.Fatalf("OpenSecretStore should not fail: %v", err)
+	}
+	if err := store.Set("registry/password", "hunter2"); err != nil {
+		t.Fatalf("Set should not fail: %v", err)
+	}
+	if err := store.Set("bad name", "x"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save should not fail: %v", err)
+	}
+
+	store, err = OpenSecretStore(false)
+	if err != nil {
+		t.Fatalf("OpenSecretStore should not fail: %v", err)
+	}
+	if value, err := store.Get("registry/password"); err != nil || value != "hunter2" {
+		t.Errorf("expected hunter2, got %q, %v", value, err)
+	}
+	var notFound *SecretNotFoundError
+	if err := store.Remove("nonexistent"); !errors.As(err, &notFound) {
+		t.Errorf("expected SecretNotFoundError, got %v", err)
+	}
+
+	t.Setenv(SecretPassphraseEnv, "wrong")
+	if _, err := OpenSecretStore(false); err == nil {
+		t.Error("expected an error for a wrong passphrase")
+	}
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// SecretPassphraseEnv is the environment variable holding the passphrase of
+// the secret store. Without it, the passphrase is asked on the terminal.
+const SecretPassphraseEnv = "PODMAN_SWARM_SECRET_PASSPHRASE"
+
+// scrypt parameters of new stores
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-/]+$`)
+
+// SecretNotFoundError is returned for an unknown secret name
+type SecretNotFoundError struct {
+	Name string
+}
+
+func (e *SecretNotFoundError) Error() string {
+	return fmt.Sprintf("secret '%s' not found", e.Name)
+}
+
+// SecretStore is a file of named secrets encrypted with a passphrase
+type SecretStore struct {
+	path    string
+	header  secretFile
+	key     []byte
+	entries map[string]secretEntry
+}
+
+type secretEntry struct {
+	Value   string    `json:"value"`
+	Updated time.Time `json:"updated"`
+}
+
+// secretFile is the on-disk format of the store. Data is the JSON encoded
+// entries, sealed with XChaCha20-Poly1305 under a key derived from the
+// passphrase with scrypt.
+type secretFile struct {
+	Version int    `json:"version"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce,omitempty"`
+	Data    []byte `json:"data,omitempty"`
+}
+
+// SecretsPath returns the path of the secret store,
+// ~/.config/podman-swarm/secrets.enc
+func SecretsPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "podman-swarm", "secrets.enc")
+}
+
+// OpenSecretStore decrypts the secret store. If it does not exist, an empty
+// store is returned when create is set; it is written on Save.
+func OpenSecretStore(create bool) (*SecretStore, error) {
+	path := SecretsPath()
+	s := &SecretStore{path: path, entries: map[string]secretEntry{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, fmt.Errorf("no secret store at %s; add a secret with 'podman-swarm secret set'", path)
+		}
+		passphrase, err := secretPassphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		s.header = secretFile{Version: 1, N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+		if _, err := rand.Read(s.header.Salt); err != nil {
+			return nil, err
+		}
+		if s.key, err = s.header.deriveKey(passphrase); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.header); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store %s: %w", path, err)
+	}
+	if s.header.Version != 1 {
+		return nil, fmt.Errorf("unsupported secret store version %d", s.header.Version)
+	}
+	passphrase, err := secretPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	if s.key, err = s.header.deriveKey(passphrase); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, s.header.Nonce, s.header.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret store: wrong passphrase or corrupted file")
+	}
+	if err := json.Unmarshal(plain, &s.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (f secretFile) deriveKey(passphrase []byte) ([]byte, error) {
+	key, err := scrypt.Key(passphrase, f.Salt, f.N, f.R, f.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+// secretPassphrase returns the passphrase of the store from
+// SecretPassphraseEnv or the terminal. A new passphrase is asked twice.
+func secretPassphrase(confirm bool) ([]byte, error) {
+	if value, ok := os.LookupEnv(SecretPassphraseEnv); ok {
+		return []byte(value), nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil || !term.IsTerminal(int(tty.Fd())) {
+		if tty != nil {
+			tty.Close()
+		}
+		return nil, fmt.Errorf("the secret store is encrypted; set $%s", SecretPassphraseEnv)
+	}
+	defer tty.Close()
+
+	prompt := "Enter passphrase for the secret store: "
+	if confirm {
+		prompt = "Enter new passphrase for the secret store: "
+	}
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	fmt.Fprint(tty, "Repeat passphrase: ")
+	again, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if string(again) != string(passphrase) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// Get returns the value of a secret
+func (s *SecretStore) Get(name string) (string, error) {
+	entry, ok := s.entries[name]
+	if !ok {
+		return "", &SecretNotFoundError{Name: name}
+	}
+	return entry.Value, nil
+}
+
+// Set adds or replaces a secret
+func (s *SecretStore) Set(name, value string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q (use letters, digits and _ . - /)", name)
+	}
+	s.entries[name] = secretEntry{Value: value, Updated: time.Now().UTC()}
+	return nil
+}
+
+// Remove deletes a secret
+func (s *SecretStore) Remove(name string) error {
+	if _, ok := s.entries[name]; !ok {
+		return &SecretNotFoundError{Name: name}
+	}
+	delete(s.entries, name)
+	return nil
+}
+
+// Names returns the names of the secrets in order
+func (s *SecretStore) Names() []string {
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Updated returns when a secret was last set
+func (s *SecretStore) Updated(name string) time.Time {
+	return s.entries[name].Updated
+}
+
+// Save encrypts and writes the store
+func (s *SecretStore) Save() error {
+	plain, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return err
+	}
+	header := s.header
+	header.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(header.Nonce); err != nil {
+		return err
+	}
+	header.Data = aead.Seal(nil, header.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save secret store: %w", err)
+	}
+	s.header = header
+	return nil
+}
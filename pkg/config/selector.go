@@ -0,0 +1,267 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// NotFoundError is returned by Select when a selector names an unknown host
+// or group, or matches no hosts
+type NotFoundError struct {
+	// Name is the unknown host or group name, if any
+	Name     string
+	Selector string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("host or group '%s' not found", e.Name)
+	}
+	return fmt.Sprintf("no hosts match '%s'", e.Selector)
+}
+
+var labelSetPattern = regexp.MustCompile(`^([^\s=!()]+)\s+(in|notin)\s*\((.*)\)$`)
+
+// Select returns the hosts matching a selector, in inventory order.
+//
+// A selector is a list of segments separated by ":". The hosts of the first
+// segment are extended by each following segment, or reduced when it is
+// prefixed with "!" (exclude) or "&" (intersect). A segment is a comma
+// separated list of terms: host names, group names and globs like "web-*"
+// are unioned, and label requirements ("env=prod", "env!=dev",
+// "zone in (a,b)", "zone notin (c)") are all required. For example
+// "web,db", "web:!host3" and "env=prod,zone in (a,b)".
+func (c *Config) Select(selector string) ([]*Host, error) {
+	segments := splitTopLevel(selector, ':')
+
+	var selected map[string]bool
+	for i, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		op := byte(0)
+		if strings.HasPrefix(segment, "!") || strings.HasPrefix(segment, "&") {
+			op = segment[0]
+			segment = strings.TrimSpace(segment[1:])
+		}
+
+		matched, err := c.selectSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			selected = map[string]bool{}
+			if op == '!' {
+				// A leading exclusion applies to every host
+				for _, h := range c.Hosts {
+					selected[h.Name] = true
+				}
+			} else {
+				for name := range matched {
+					selected[name] = true
+				}
+				continue
+			}
+		}
+
+		switch op {
+		case '!':
+			for name := range matched {
+				delete(selected, name)
+			}
+		case '&':
+			for name := range selected {
+				if !matched[name] {
+					delete(selected, name)
+				}
+			}
+		default:
+			for name := range matched {
+				selected[name] = true
+			}
+		}
+	}
+
+	var hosts []*Host
+	for i := range c.Hosts {
+		if selected[c.Hosts[i].Name] {
+			h := c.Hosts[i]
+			hosts = append(hosts, &h)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, &NotFoundError{Selector: selector}
+	}
+	return hosts, nil
+}
+
+// selectSegment returns the names of the hosts matching one segment
+func (c *Config) selectSegment(segment string) (map[string]bool, error) {
+	if segment == "" {
+		return nil, fmt.Errorf("empty term in selector")
+	}
+
+	var names []string
+	var requirements []labelRequirement
+	for _, term := range splitTopLevel(segment, ',') {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty term in selector '%s'", segment)
+		}
+		req, ok, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			requirements = append(requirements, req)
+		} else {
+			names = append(names, term)
+		}
+	}
+
+	matched := map[string]bool{}
+	if len(names) == 0 {
+		for _, h := range c.Hosts {
+			matched[h.Name] = true
+		}
+	}
+	for _, name := range names {
+		hosts, err := c.selectName(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hosts {
+			matched[h] = true
+		}
+	}
+
+	for _, h := range c.Hosts {
+		if !matched[h.Name] {
+			continue
+		}
+		for _, req := range requirements {
+			if !req.matches(h.Labels) {
+				delete(matched, h.Name)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// selectName returns the host names for a host name, group name or glob
+func (c *Config) selectName(name string) ([]string, error) {
+	if !strings.ContainsAny(name, "*?[") {
+		hosts := c.GetHostOrGroup(name)
+		if hosts == nil {
+			return nil, &NotFoundError{Name: name}
+		}
+		names := make([]string, len(hosts))
+		for i, h := range hosts {
+			names[i] = h.Name
+		}
+		return names, nil
+	}
+
+	if _, err := path.Match(name, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s' in selector", name)
+	}
+	var names []string
+	for _, h := range c.Hosts {
+		if ok, _ := path.Match(name, h.Name); ok {
+			names = append(names, h.Name)
+		}
+	}
+	for _, g := range c.Groups {
+		if ok, _ := path.Match(name, g.Name); ok {
+			for _, h := range c.GetHostsByGroup(g.Name) {
+				names = append(names, h.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// labelRequirement is a condition on one label
+type labelRequirement struct {
+	key    string
+	values []string
+	negate bool
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	found := false
+	if ok {
+		for _, v := range r.values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+	}
+	return found != r.negate
+}
+
+// parseLabelRequirement parses a label requirement, reporting false if term
+// is not one
+func parseLabelRequirement(term string) (labelRequirement, bool, error) {
+	if m := labelSetPattern.FindStringSubmatch(term); m != nil {
+		var values []string
+		for _, v := range strings.Split(m[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return labelRequirement{}, false, fmt.Errorf("empty value list in selector term '%s'", term)
+		}
+		return labelRequirement{key: m[1], values: values, negate: m[2] == "notin"}, true, nil
+	}
+
+	op, negate := "", false
+	switch {
+	case strings.Contains(term, "!="):
+		op, negate = "!=", true
+	case strings.Contains(term, "=="):
+		op = "=="
+	case strings.Contains(term, "="):
+		op = "="
+	default:
+		if strings.ContainsAny(term, "() ") {
+			return labelRequirement{}, false, fmt.Errorf("invalid selector term '%s'", term)
+		}
+		return labelRequirement{}, false, nil
+	}
+
+	i := strings.Index(term, op)
+	key := strings.TrimSpace(term[:i])
+	value := strings.TrimSpace(term[i+len(op):])
+	if key == "" {
+		return labelRequirement{}, false, fmt.Errorf("missing label name in selector term '%s'", term)
+	}
+	return labelRequirement{key: key, values: []string{value}, negate: negate}, true, nil
+}
+
+// splitTopLevel splits s at sep, ignoring separators inside parentheses
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func selectorTestConfig() *Config {
+	return &Config{
+		Hosts: []Host{
+			{Name: "web-1", Labels: map[string]string{"env": "prod", "zone": "a"}},
+			{Name: "web-2", Labels: map[string]string{"env": "prod", "zone": "b"}},
+			{Name: "web-3", Labels: map[string]string{"env": "staging", "zone": "c"}},
+			{Name: "db-1", Labels: map[string]string{"env": "prod", "zone": "a", "arch": "arm64"}},
+			{Name: "host3"},
+		},
+		Groups: []HostGroup{
+			{Name: "web", Hosts: []string{"web-1", "web-2", "web-3", "host3"}},
+			{Name: "db", Hosts: []string{"db-1"}},
+		},
+	}
+}
+
+// TestSelect verifies selector expressions
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		selector string
+		expected []string
+	}{
+		{"web-1", []string{"web-1"}},
+		{"db", []string{"db-1"}},
+		{"web,db", []string{"web-1", "web-2", "web-3", "db-1", "host3"}},
+		{"web:!host3", []string{"web-1", "web-2", "web-3"}},
+		{"web:&env=prod", []string{"web-1", "web-2"}},
+		{"web-*", []string{"web-1", "web-2", "web-3"}},
+		{"web-*:db", []string{"web-1", "web-2", "web-3", "db-1"}},
+		{"env=prod", []string{"web-1", "web-2", "db-1"}},
+		{"env=prod,zone in (a,b)", []string{"web-1", "web-2", "db-1"}},
+		{"env==prod,zone in (b, c)", []string{"web-2"}},
+		{"zone notin (a)", []string{"web-2", "web-3", "host3"}},
+		{"web,env!=prod", []string{"web-3", "host3"}},
+		{"!web", []string{"db-1"}},
+		{"arch=arm64:web-1", []string{"web-1", "db-1"}},
+	}
+
+	cfg := selectorTestConfig()
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			hosts, err := cfg.Select(tc.selector)
+			if err != nil {
+				t.Fatalf("Select should not fail: %v", err)
+			}
+			var names []string
+			for _, h := range hosts {
+				names = append(names, h.Name)
+			}
+			// Results are in inventory order
+			var expected []string
+			for _, h := range cfg.Hosts {
+				for _, name := range tc.expected {
+					if h.Name == name {
+						expected = append(expected, name)
+					}
+				}
+			}
+			if !reflect.DeepEqual(names, expected) {
+				t.Errorf("expected %v, got: %v", expected, names)
+			}
+		})
+	}
+}
+
+// TestSelect_Errors verifies unknown names, empty results and syntax errors
+func TestSelect_Errors(t *testing.T) {
+	cfg := selectorTestConfig()
+
+	var notFound *NotFoundError
+	if _, err := cfg.Select("web,wbe"); !errors.As(err, &notFound) || notFound.Name != "wbe" {
+		t.Errorf("unknown name should be reported, got: %v", err)
+	}
+	if _, err := cfg.Select("env=qa"); !errors.As(err, &notFound) {
+		t.Errorf("empty selection should be reported as not found, got: %v", err)
+	}
+	if _, err := cfg.Select("nothing-*"); !errors.As(err, &notFound) {
+		t.Errorf("glob without matches should be reported as not found, got: %v", err)
+	}
+
+	for _, selector := range []string{"", "web,", "web:", "=prod", "zone in ()", "web-[", "zone in (a"} {
+		if _, err := cfg.Select(selector); err == nil {
+			t.Errorf("selector %q should be rejected", selector)
+		}
+	}
+}
+
+// TestLoad_Labels verifies that labels are loaded from the inventory
+func TestLoad_Labels(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+hosts:
+  - name: app1
+    address: 10.0.1.10
+    username: ubuntu
+    private_key: /keys/app
+    labels:
+      env: prod
+      zone: 1
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	hosts, err := cfg.Select("env=prod,zone=1")
+	if err != nil || len(hosts) != 1 {
+		t.Errorf("expected app1 to match, got: %v, %v", hosts, err)
+	}
+}
+
+// TestLoad_LabelCase verifies that label keys keep their case, so that
+// selectors match them as written
+func TestLoad_LabelCase(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+defaults:
+  labels:
+    Team: Core
+hosts:
+  - name: app1
+    address: 10.0.1.10
+    labels:
+      Env: Prod
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	hosts, err := cfg.Select("Env=Prod,Team=Core")
+	if err != nil || len(hosts) != 1 {
+		t.Errorf("expected app1 to match, got: %v, %v", hosts, err)
+	}
+	if hosts, _ := cfg.Select("env=Prod"); len(hosts) != 0 {
+		t.Errorf("label keys should be case-sensitive, got: %v", hosts)
+	}
+}
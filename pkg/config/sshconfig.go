@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSSHConfigFile returns the path of the user's OpenSSH client
+// configuration, ~/.ssh/config
+func DefaultSSHConfigFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ssh", "config")
+}
+
+// sshConfigBlock is a Host block of an OpenSSH client configuration
+type sshConfigBlock struct {
+	patterns []string
+	// options holds the values of each keyword, lowercased, in file order
+	options map[string][]string
+}
+
+// sshConfig is a parsed OpenSSH client configuration. Only Host blocks are
+// supported; Match blocks are skipped.
+type sshConfig struct {
+	blocks []sshConfigBlock
+}
+
+// parseSSHConfig reads an OpenSSH client configuration, following Include
+// directives
+func parseSSHConfig(file string) (*sshConfig, error) {
+	cfg := &sshConfig{}
+	// Options before the first Host line apply to every host
+	cfg.blocks = append(cfg.blocks, sshConfigBlock{patterns: []string{"*"}, options: map[string][]string{}})
+	if err := cfg.parseFile(file, 0); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *sshConfig) parseFile(file string, depth int) error {
+	if depth > 16 {
+		return fmt.Errorf("%s: too many nested includes", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	skip := false
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			if len(args) == 0 {
+				return fmt.Errorf("%s:%d: Host without patterns", file, lineNo)
+			}
+			skip = false
+			c.blocks = append(c.blocks, sshConfigBlock{patterns: args, options: map[string][]string{}})
+		case "match":
+			skip = true
+		case "include":
+			for _, pattern := range args {
+				pattern = expandPath(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(filepath.Dir(DefaultSSHConfigFile()), pattern)
+				}
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+				}
+				for _, m := range matches {
+					if err := c.parseFile(m, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			if skip || len(args) == 0 {
+				continue
+			}
+			block := &c.blocks[len(c.blocks)-1]
+			block.options[keyword] = append(block.options[keyword], strings.Join(args, " "))
+		}
+	}
+	return scanner.Err()
+}
+
+// splitSSHConfigLine returns the lowercased keyword of a line and its
+// arguments, unquoting them
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	// The keyword may be separated by whitespace or a single "="
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimSpace(line[end:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				arg, rest = rest[1:], ""
+			} else {
+				arg, rest = rest[1:closing+1], rest[closing+2:]
+			}
+		} else if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			arg, rest = rest[:i], rest[i:]
+		} else {
+			arg, rest = rest, ""
+		}
+		args = append(args, arg)
+		rest = strings.TrimSpace(rest)
+	}
+	return keyword, args
+}
+
+// matches reports whether alias matches the block's patterns. A negated
+// pattern that matches excludes the alias.
+func (b sshConfigBlock) matches(alias string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		negate := strings.HasPrefix(p, "!")
+		ok, _ := path.Match(strings.TrimPrefix(p, "!"), alias)
+		if ok && negate {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// explicit reports whether a block names alias without wildcards
+func (b sshConfigBlock) explicit(alias string) bool {
+	for _, p := range b.patterns {
+		if p == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the first value of keyword for alias, as ssh does
+func (c *sshConfig) lookup(alias, keyword string) (string, bool) {
+	for _, b := range c.blocks {
+		if !b.matches(alias) {
+			continue
+		}
+		if values := b.options[keyword]; len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// known reports whether a Host block names alias explicitly
+func (c *sshConfig) known(alias string) bool {
+	for _, b := range c.blocks {
+		if b.explicit(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostVars returns the podman-swarm settings for an ssh_config alias, with
+// keys as in hosts.yaml
+func (c *sshConfig) hostVars(alias string, isInventoryHost func(string) bool) map[string]interface{} {
+	vars := map[string]interface{}{}
+
+	address := alias
+	if hostname, ok := c.lookup(alias, "hostname"); ok {
+		address = strings.ReplaceAll(strings.ReplaceAll(hostname, "%h", alias), "%%", "%")
+	}
+	vars["address"] = address
+	if port, ok := c.lookup(alias, "port"); ok {
+		vars["port"] = port
+	}
+	if user, ok := c.lookup(alias, "user"); ok {
+		vars["username"] = user
+	}
+	if identity, ok := c.lookup(alias, "identityfile"); ok {
+		vars["private_key"] = identity
+	}
+	if cert, ok := c.lookup(alias, "certificatefile"); ok {
+		vars["certificate"] = cert
+	}
+	if knownHosts, ok := c.lookup(alias, "userknownhostsfile"); ok && knownHosts != "none" {
+		vars["known_hosts"] = strings.Fields(knownHosts)[0]
+	}
+	if proxyJump, ok := c.lookup(alias, "proxyjump"); ok && proxyJump != "none" {
+		var jump []interface{}
+		for _, spec := range strings.Split(proxyJump, ",") {
+			jump = append(jump, c.jumpHop(strings.TrimSpace(spec), isInventoryHost))
+		}
+		vars["jump"] = jump
+	}
+	return vars
+}
+
+// jumpHop converts a ProxyJump hop. Hops naming another ssh_config alias get
+// that alias' connection settings; inventory host names are kept as
+// references.
+func (c *sshConfig) jumpHop(spec string, isInventoryHost func(string) bool) interface{} {
+	hop, err := parseJumpSpec(strings.TrimPrefix(spec, "ssh://"))
+	if err != nil {
+		return spec
+	}
+	alias := hop.Ref
+	if alias == "" {
+		alias = hop.Address
+	}
+	if hop.Ref != "" && isInventoryHost(hop.Ref) {
+		return spec
+	}
+	if !c.known(alias) {
+		return spec
+	}
+
+	vars := c.hostVars(alias, isInventoryHost)
+	delete(vars, "jump")
+	if hop.Username != "" {
+		vars["username"] = hop.Username
+	}
+	if hop.Port != 0 {
+		vars["port"] = hop.Port
+	}
+	return vars
+}
+
+// sshConfigVars returns the settings each host gets from its
+// ssh_config_host entry, and the aliases not found in the ssh_config file
+func (c *Config) sshConfigVars() (map[string]varLayer, []string, error) {
+	var parsed *sshConfig
+	layers := map[string]varLayer{}
+	var unknown []string
+	for _, h := range c.Hosts {
+		if h.SSHConfigHost == "" {
+			continue
+		}
+		if parsed == nil {
+			file := expandPath(c.SSHConfigFile)
+			if file == "" {
+				file = DefaultSSHConfigFile()
+			}
+			var err error
+			if parsed, err = parseSSHConfig(file); err != nil {
+				return nil, nil, fmt.Errorf("failed to read ssh config: %w", err)
+			}
+		}
+
+		if !parsed.known(h.SSHConfigHost) {
+			unknown = append(unknown, h.SSHConfigHost)
+		}
+		layers[h.Name] = varLayer{
+			origin:  originSSHConfigPrefix + h.SSHConfigHost,
+			vars:    parsed.hostVars(h.SSHConfigHost, func(name string) bool { return c.GetHostByName(name) != nil }),
+			perHost: true,
+		}
+	}
+	return layers, unknown, nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoad_SSHConfigHost verifies that connection settings are taken from
+// ssh_config, with inventory settings taking precedence
+func TestLoad_SSHConfigHost(t *testing.T) {
+	dir := t.TempDir()
+	sshConfig := filepath.Join(dir, "ssh_config")
+	err := os.WriteFile(sshConfig, []byte(`
+# Options before the first Host apply to every host
+UserKnownHostsFile /keys/known_hosts
+
+Host bastion
+    HostName 203.0.113.1
+    User jump
+    IdentityFile /keys/jump
+
+Host app-*
+    HostName %h.internal.example.com
+    ProxyJump bastion
+
+Host app-1
+    User = "deploy"
+    Port 2222
+    IdentityFile /keys/app
+
+Host *
+    IdentityFile /keys/default
+
+Match host *.example.com
+    User ignored
+`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write ssh config: %v", err)
+	}
+
+	cfg, err := loadTestConfig(t, `
+ssh_config_file: `+sshConfig+`
+defaults:
+  username: ubuntu
+hosts:
+  - name: app1
+    ssh_config_host: app-1
+  - name: app2
+    ssh_config_host: app-2
+    port: 22
+`)
+	if err != nil {
+		t.Fatalf("Load should not fail: %v", err)
+	}
+
+	app1 := cfg.GetHostByName("app1")
+	if app1.Address != "app-1.internal.example.com" || app1.Port != 2222 || app1.Username != "deploy" || app1.PrivateKey != "/keys/app" || app1.KnownHosts != "/keys/known_hosts" {
+		t.Errorf("app1 should take its settings from ssh_config, got: %+v", app1)
+	}
+	if len(app1.Jump) != 1 || app1.Jump[0].Address != "203.0.113.1" || app1.Jump[0].Username != "jump" || app1.Jump[0].PrivateKey != "/keys/jump" {
+		t.Errorf("ProxyJump should use the settings of the bastion alias, got: %+v", app1.Jump)
+	}
+
+	app2 := cfg.GetHostByName("app2")
+	if app2.Port != 22 || app2.Username != "ubuntu" || app2.PrivateKey != "/keys/default" {
+		t.Errorf("app2 should keep its own port and fall back to defaults, got: %+v", app2)
+	}
+
+	settings, _ := cfg.Settings("app1")
+	for _, s := range settings {
+		if s.Key == "address" && s.Origin != "ssh_config app-1" {
+			t.Errorf("address should come from ssh_config, got: %s", s.Origin)
+		}
+	}
+}
+
+// TestSplitSSHConfigLine verifies keyword and argument parsing
+func TestSplitSSHConfigLine(t *testing.T) {
+	testCases := []struct {
+		line    string
+		keyword string
+		args    []string
+	}{
+		{"  HostName example.com", "hostname", []string{"example.com"}},
+		{"User=deploy", "user", []string{"deploy"}},
+		{`IdentityFile "/path/with space/key"`, "identityfile", []string{"/path/with space/key"}},
+		{"Host web-* !web-3", "host", []string{"web-*", "!web-3"}},
+		{"# comment", "", nil},
+	}
+
+	for _, tc := range testCases {
+		keyword, args := splitSSHConfigLine(tc.line)
+		if keyword != tc.keyword || len(args) != len(tc.args) {
+			t.Errorf("%q: expected %s %q, got: %s %q", tc.line, tc.keyword, tc.args, keyword, args)
+			continue
+		}
+		for i := range args {
+			if args[i] != tc.args[i] {
+				t.Errorf("%q: expected %q, got: %q", tc.line, tc.args, args)
+			}
+		}
+	}
+}
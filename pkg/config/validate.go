@@ -0,0 +1,361 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity tells whether a Problem makes the configuration unusable
+type Severity int
+
+const (
+	// Warning is a suspicious setting that does not prevent loading
+	Warning Severity = iota
+	// Error makes Load fail
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is an issue found in the configuration file
+type Problem struct {
+	Severity Severity
+	File     string
+	// Line is the 1-based line the problem was found at, or 0 if unknown
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Message)
+}
+
+// ValidationError is returned by Load when the configuration has errors
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := []string{"invalid configuration:"}
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate loads the configuration from the default path and returns every
+// problem found, including warnings. An error is returned only if the file
+// cannot be read or parsed.
+func Validate() ([]Problem, error) {
+	_, problems, err := load()
+	return problems, err
+}
+
+// validator collects problems, locating them in the YAML documents
+type validator struct {
+	*sources
+	problems []Problem
+}
+
+// location is a node of a document
+type location struct {
+	file string
+	node *yaml.Node
+}
+
+func (l location) line() int {
+	if l.node == nil {
+		return 0
+	}
+	return l.node.Line
+}
+
+// position describes l for a problem found in file
+func (l location) position(file string) string {
+	if l.file == file {
+		return fmt.Sprintf("line %d", l.line())
+	}
+	return fmt.Sprintf("%s:%d", l.file, l.line())
+}
+
+func (v *validator) report(severity Severity, loc location, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		Severity: severity,
+		File:     loc.file,
+		Line:     loc.line(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// host returns the location of path in the i-th host, e.g. ("port")
+func (v *validator) host(i int, path ...interface{}) location {
+	return v.locate("hosts", v.hosts, i, path)
+}
+
+// group returns the location of path in the i-th group
+func (v *validator) group(i int, path ...interface{}) location {
+	return v.locate("groups", v.groups, i, path)
+}
+
+func (v *validator) locate(key string, entries []entry, i int, path []interface{}) location {
+	if i >= len(entries) {
+		main := v.docs[0]
+		return location{main.file, walk(main.root, key)}
+	}
+	e := entries[i]
+	doc := v.docs[e.doc]
+	return location{doc.file, walk(doc.root, append([]interface{}{key, e.index}, path...)...)}
+}
+
+// walk returns the node at path, e.g. ("hosts", 2, "port"), or the closest
+// existing parent
+func walk(node *yaml.Node, path ...interface{}) *yaml.Node {
+	for _, p := range path {
+		var next *yaml.Node
+		switch p := p.(type) {
+		case string:
+			next = mappingValue(node, p)
+		case int:
+			if node != nil && node.Kind == yaml.SequenceNode && p < len(node.Content) {
+				next = node.Content[p]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// mappingValue returns the value of key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// parseDocument parses data into the node of its top-level mapping
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// validate checks cfg, merged from the documents of s, for problems
+func (c *Config) validate(s *sources) []Problem {
+	v := &validator{sources: s}
+	for _, doc := range s.docs {
+		v.checkKeys(doc.file, doc.root, reflect.TypeOf(Config{}), "")
+	}
+	c.validateVars(v)
+	c.validateHosts(v)
+	c.validateGroups(v)
+	return v.problems
+}
+
+// validateVars reports per-host settings in defaults and group vars, which
+// are not inherited
+func (c *Config) validateVars(v *validator) {
+	for _, doc := range v.docs {
+		check := func(node *yaml.Node, where string) {
+			for _, key := range []string{"name", "address"} {
+				if value := mappingValue(node, key); value != nil {
+					v.report(Warning, location{doc.file, value}, "%s in %s is ignored; set it on each host", key, where)
+				}
+			}
+		}
+		check(mappingValue(doc.root, "defaults"), "defaults")
+		if groups := mappingValue(doc.root, "groups"); groups != nil && groups.Kind == yaml.SequenceNode {
+			for _, g := range groups.Content {
+				if name := mappingValue(g, "name"); name != nil {
+					check(mappingValue(g, "vars"), "vars of group "+name.Value)
+				}
+			}
+		}
+	}
+}
+
+func (c *Config) validateHosts(v *validator) {
+	first := map[string]location{}
+	for i, h := range c.Hosts {
+		node := v.host(i)
+		if h.Name == "" {
+			v.report(Error, node, "host #%d has no name", i+1)
+			continue
+		}
+		name := v.host(i, "name")
+		if loc, ok := first[h.Name]; ok {
+			v.report(Error, name, "host %s is defined more than once (first at %s)", h.Name, loc.position(name.file))
+		} else {
+			first[h.Name] = name
+		}
+
+		for _, alias := range c.unknownSSHHosts {
+			if alias == h.SSHConfigHost {
+				v.report(Warning, v.host(i, "ssh_config_host"), "ssh_config_host %s of host %s has no Host entry in the ssh config", alias, h.Name)
+				break
+			}
+		}
+		if h.Address == "" {
+			v.report(Error, node, "host %s has no address", h.Name)
+		}
+		if h.Port < 0 || h.Port > 65535 {
+			v.report(Error, v.host(i, "port"), "host %s has invalid port %d", h.Name, h.Port)
+		}
+		for j, method := range h.AuthOrder {
+			if !validAuthMethod(method) {
+				v.report(Error, v.host(i, "auth_order", j), "unknown auth_order method %q for host %s (expected agent, certificate or key)", method, h.Name)
+			}
+		}
+		if !validPodmanBackend(h.PodmanBackend) {
+			v.report(Error, v.host(i, "podman_backend"), "unknown podman_backend %q for host %s (expected cli or api)", h.PodmanBackend, h.Name)
+		}
+		if h.PrivateKey != "" {
+			if _, err := os.Stat(h.PrivateKey); err != nil {
+				v.report(Warning, v.host(i, "private_key"), "private_key of host %s: %v", h.Name, err)
+			}
+		}
+		if h.Passphrase != "" && !h.Passphrase.IsReference() {
+			v.report(Warning, v.host(i, "passphrase"), "passphrase of host %s is in plain text; use a secret://, env:// or cmd:// reference", h.Name)
+		}
+		for _, registry := range sortedKeys(h.Registries) {
+			credentials := h.Registries[registry]
+			if credentials != "" && !credentials.IsReference() {
+				v.report(Warning, v.host(i, "registries", registry), "credentials for %s of host %s are in plain text; use a secret://, env:// or cmd:// reference", registry, h.Name)
+			}
+		}
+		if h.Certificate != "" {
+			if _, err := os.Stat(h.Certificate); err != nil {
+				v.report(Warning, v.host(i, "certificate"), "certificate of host %s: %v", h.Name, err)
+			}
+		}
+	}
+}
+
+func (c *Config) validateGroups(v *validator) {
+	seen := map[string]bool{}
+	for i, g := range c.Groups {
+		node := v.group(i)
+		if g.Name == "" {
+			v.report(Error, node, "group #%d has no name", i+1)
+			continue
+		}
+		if seen[g.Name] {
+			v.report(Error, v.group(i, "name"), "group %s is defined more than once", g.Name)
+		}
+		seen[g.Name] = true
+
+		if c.GetHostByName(g.Name) != nil {
+			v.report(Warning, v.group(i, "name"), "group %s has the same name as a host; the host takes precedence", g.Name)
+		}
+		for j, name := range g.Hosts {
+			if c.GetHostByName(name) == nil {
+				v.report(Error, v.group(i, "hosts", j), "group %s refers to unknown host %q", g.Name, name)
+			}
+		}
+		for j, name := range g.Children {
+			if c.group(name) == nil && name != AllGroup {
+				v.report(Error, v.group(i, "children", j), "group %s refers to unknown child group %q", g.Name, name)
+			}
+		}
+	}
+
+	for _, cycle := range c.groupCycles() {
+		i := 0
+		for i < len(c.Groups) && c.Groups[i].Name != cycle[0] {
+			i++
+		}
+		v.report(Error, v.group(i, "children"), "group cycle: %s", strings.Join(cycle, " -> "))
+	}
+}
+
+// checkKeys reports keys of node that do not correspond to a field of t
+func (v *validator) checkKeys(file string, node *yaml.Node, t reflect.Type, where string) {
+	if node == nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		v.checkKeys(file, node, t.Elem(), where)
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			v.checkKeys(file, item, t.Elem(), fmt.Sprintf("%s[%d]", where, i))
+		}
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := structFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, ok := fields[key]
+			if !ok {
+				if where == "" {
+					v.report(Warning, location{file, node.Content[i]}, "unknown key %q", key)
+				} else {
+					v.report(Warning, location{file, node.Content[i]}, "unknown key %q in %s", key, where)
+				}
+				continue
+			}
+			path := key
+			if where != "" {
+				path = where + "." + key
+			}
+			v.checkKeys(file, node.Content[i+1], field, path)
+		}
+	}
+}
+
+// structFields returns the types of the fields of t by their configuration
+// key, including the fields of squashed structs
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" {
+			tag = f.Tag.Get("yaml")
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "squash") || strings.Contains(opts, "inline") {
+			for k, v := range structFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if name != "-" {
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validateTestConfig writes content to a temporary hosts.yaml and validates it
+func validateTestConfig(t *testing.T, content string) []Problem {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("PODMAN_SWARM_CONFIG", path)
+
+	problems, err := Validate()
+	if err != nil {
+		t.Fatalf("Validate should not fail: %v", err)
+	}
+	return problems
+}
+
+// TestValidate verifies that every problem is reported with its line
+func TestValidate(t *testing.T) {
+	problems := validateTestConfig(t, `hosts:
+  - name: web1
+    address: 10.0.0.1
+    username: ubuntu
+    private_key: /nonexistent/id_ed25519
+  - name: web1
+    address: 10.0.0.2
+    port: 70000
+    username: ubuntu
+    private_kye: ~/.ssh/id_rsa
+  - name: db
+    username: ubuntu
+groups:
+  - name: db
+    hosts: [web1, wbe2]
+colour: blue
+`)
+
+	expected := []struct {
+		severity Severity
+		line     int
+		message  string
+	}{
+		{Warning, 10, `unknown key "private_kye" in hosts[1]`},
+		{Warning, 16, `unknown key "colour"`},
+		{Warning, 5, "private_key of host web1"},
+		{Error, 6, "host web1 is defined more than once (first at line 2)"},
+		{Error, 8, "host web1 has invalid port 70000"},
+		{Error, 11, "host db has no address"},
+		{Warning, 14, "group db has the same name as a host"},
+		{Error, 15, `group db refers to unknown host "wbe2"`},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got: %v", len(expected), problems)
+	}
+	for i, want := range expected {
+		p := problems[i]
+		if p.Severity != want.severity || p.Line != want.line || !strings.Contains(p.Message, want.message) {
+			t.Errorf("problem %d: expected %s at line %d: %q, got: %s", i, want.severity, want.line, want.message, p)
+		}
+	}
+}
+
+// TestLoad_Warnings verifies that warnings do not prevent loading
+func TestLoad_Warnings(t *testing.T) {
+	_, err := loadTestConfig(t, `
+hosts:
+  - name: web1
+    address: 10.0.0.1
+    username: ubuntu
+    tags: [web]
+`)
+	if err != nil {
+		t.Errorf("Load should ignore warnings, got: %v", err)
+	}
+}
+
+// TestValidate_PodmanBackend verifies that unknown backends are rejected
+func TestValidate_PodmanBackend(t *testing.T) {
+	problems := validateTestConfig(t, `defaults:
+  podman_backend: api
+hosts:
+  - name: web1
+    address: 10.0.0.1
+  - name: web2
+    address: 10.0.0.2
+    podman_backend: rest
+`)
+
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got: %v", problems)
+	}
+	if p := problems[0]; p.Severity != Error || p.Line != 8 || !strings.Contains(p.Message, `unknown podman_backend "rest" for host web2`) {
+		t.Errorf("unexpected problem: %s", p)
+	}
+}
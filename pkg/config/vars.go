@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Origins of host settings, as reported by Settings
+const (
+	OriginHost     = "host"
+	OriginDefaults = "defaults"
+	// Group origins are "group <name>"
+	originGroupPrefix = "group "
+	// ssh_config origins are "ssh_config <alias>"
+	originSSHConfigPrefix = "ssh_config "
+)
+
+// varLayer is a source of inherited host settings
+type varLayer struct {
+	origin string
+	vars   map[string]interface{}
+	// perHost layers apply to a single host and may set its address
+	perHost bool
+}
+
+// applyVars fills the settings a host does not set itself from hostLayers,
+// then from the vars of its groups and then from defaults. The vars of a
+// group override those of the groups including it; between unrelated
+// groups, the one listed later wins. It returns the origin of every setting
+// of each host by name.
+func (c *Config) applyVars(settings map[string]interface{}, hostLayers map[string]varLayer) map[string]map[string]string {
+	defaults, _ := settings["defaults"].(map[string]interface{})
+	groupVars := map[string]map[string]interface{}{}
+	rawGroups, _ := settings["groups"].([]interface{})
+	for j, raw := range rawGroups {
+		group, _ := raw.(map[string]interface{})
+		if vars, ok := group["vars"].(map[string]interface{}); ok && j < len(c.Groups) {
+			groupVars[c.Groups[j].Name] = vars
+		}
+	}
+
+	origins := map[string]map[string]string{}
+	rawHosts, _ := settings["hosts"].([]interface{})
+	for _, raw := range rawHosts {
+		host, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := host["name"].(string)
+		origin := map[string]string{}
+		for key := range host {
+			origin[key] = OriginHost
+		}
+		for _, key := range mapSettings {
+			if values, ok := host[key].(map[string]interface{}); ok {
+				for k := range values {
+					origin[key+"."+k] = OriginHost
+				}
+			}
+		}
+
+		layers := c.varLayers(name, groupVars, defaults)
+		if layer, ok := hostLayers[name]; ok {
+			layers = append([]varLayer{layer}, layers...)
+		}
+		for _, layer := range layers {
+			for key, value := range layer.vars {
+				switch key {
+				case "name":
+					continue
+				case "address":
+					if !layer.perHost {
+						continue
+					}
+				case "labels", "registries":
+					inheritMap(host, key, value, layer.origin, origin)
+					continue
+				case "jump":
+					// Do not make a bastion jump through itself
+					if jumpsThrough(value, name) {
+						continue
+					}
+				}
+				if _, ok := host[key]; ok {
+					continue
+				}
+				host[key] = value
+				origin[key] = layer.origin
+			}
+		}
+		origins[name] = origin
+	}
+	return origins
+}
+
+// mapSettings are the host settings inherited key by key
+var mapSettings = []string{"labels", "registries"}
+
+// inheritMap adds the entries of the map setting key host does not have yet
+func inheritMap(host map[string]interface{}, key string, value interface{}, layerOrigin string, origin map[string]string) {
+	inherited, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	values, ok := host[key].(map[string]interface{})
+	if !ok {
+		values = map[string]interface{}{}
+		host[key] = values
+		origin[key] = layerOrigin
+	}
+	for k, v := range inherited {
+		if _, ok := values[k]; !ok {
+			values[k] = v
+			origin[key+"."+k] = layerOrigin
+		}
+	}
+}
+
+// jumpsThrough reports whether a raw jump list refers to the named host
+func jumpsThrough(value interface{}, name string) bool {
+	hops, _ := value.([]interface{})
+	for _, hop := range hops {
+		switch hop := hop.(type) {
+		case string:
+			if hop == name {
+				return true
+			}
+		case map[string]interface{}:
+			if hop["host"] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varLayers returns the vars applying to a host, most specific first
+func (c *Config) varLayers(hostName string, groupVars map[string]map[string]interface{}, defaults map[string]interface{}) []varLayer {
+	type member struct {
+		index, depth int
+	}
+	var members []member
+	for i := range c.Groups {
+		if groupVars[c.Groups[i].Name] == nil {
+			continue
+		}
+		for _, name := range c.groupHostNames(&c.Groups[i], nil, map[string]bool{}, map[string]bool{}) {
+			if name == hostName {
+				members = append(members, member{i, c.groupDepth(c.Groups[i].Name, map[string]bool{})})
+				break
+			}
+		}
+	}
+	sort.SliceStable(members, func(a, b int) bool {
+		if members[a].depth != members[b].depth {
+			return members[a].depth > members[b].depth
+		}
+		return members[a].index > members[b].index
+	})
+
+	var layers []varLayer
+	for _, m := range members {
+		g := c.Groups[m.index]
+		layers = append(layers, varLayer{origin: originGroupPrefix + g.Name, vars: groupVars[g.Name]})
+	}
+	if defaults != nil {
+		layers = append(layers, varLayer{origin: OriginDefaults, vars: defaults})
+	}
+	return layers
+}
+
+// groupDepth returns how deeply a group is nested in other groups
+func (c *Config) groupDepth(name string, visiting map[string]bool) int {
+	if visiting[name] {
+		return 0
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	depth := 0
+	for _, g := range c.Groups {
+		for _, child := range g.Children {
+			if child == name {
+				if d := c.groupDepth(g.Name, visiting) + 1; d > depth {
+					depth = d
+				}
+			}
+		}
+	}
+	return depth
+}
+
+// Setting is an effective setting of a host
+type Setting struct {
+	Key   string
+	Value string
+	// Origin is OriginHost, OriginDefaults, "group <name>" or
+	// "ssh_config <alias>"
+	Origin string
+}
+
+// Settings returns the effective settings of the named host, in the order
+// of the Host fields, and where each came from. Unset settings are omitted.
+func (c *Config) Settings(name string) ([]Setting, error) {
+	host := c.GetHostByName(name)
+	if host == nil {
+		return nil, &NotFoundError{Name: name}
+	}
+	origin := c.origins[name]
+
+	var settings []Setting
+	v := reflect.ValueOf(*host)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("mapstructure"), ",")
+		field := v.Field(i)
+		if key == "" || field.IsZero() {
+			continue
+		}
+
+		switch value := field.Interface().(type) {
+		case map[string]string:
+			for _, k := range sortedKeys(value) {
+				settings = append(settings, Setting{Key: key + "." + k, Value: value[k], Origin: origin[key+"."+k]})
+			}
+			continue
+		case map[string]Secret:
+			for _, k := range sortedKeys(value) {
+				settings = append(settings, Setting{Key: key + "." + k, Value: value[k].String(), Origin: origin[key+"."+k]})
+			}
+			continue
+		case []JumpHost:
+			hops := make([]string, len(value))
+			for j, hop := range value {
+				hops[j] = hop.String()
+			}
+			settings = append(settings, Setting{Key: key, Value: strings.Join(hops, " -> "), Origin: origin[key]})
+		case []string:
+			settings = append(settings, Setting{Key: key, Value: strings.Join(value, ", "), Origin: origin[key]})
+		default:
+			settings = append(settings, Setting{Key: key, Value: fmt.Sprint(value), Origin: origin[key]})
+		}
+	}
+	return settings, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,534 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// defaultAPIVersion is used when podman does not report its API version
+const defaultAPIVersion = "4.0.0"
+
+// APIError is an error response of the libpod REST API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("podman API: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// apiBackend talks to the libpod REST API over a tunnelled unix socket
+type apiBackend struct {
+	http *http.Client
+	// prefix is the versioned path of the libpod endpoints, e.g.
+	// "/v4.9.3/libpod"
+	prefix string
+}
+
+// NewAPIBackend returns a Backend using the libpod REST API on the unix
+// socket at socket, reached through client. The podman socket must be active
+// on the host, e.g. with systemctl --user enable --now podman.socket.
+func NewAPIBackend(ctx context.Context, client ssh.Client, socket string) (Backend, error) {
+	dialer, ok := client.(ssh.SocketDialer)
+	if !ok {
+		return nil, errors.New("the SSH client cannot connect to unix sockets")
+	}
+	if socket == "" {
+		var err error
+		if socket, err = remoteSocket(ctx, client); err != nil {
+			return nil, err
+		}
+	}
+	return newAPIBackend(ctx, func(ctx context.Context) (net.Conn, error) {
+		return dialer.DialSocket(ctx, socket)
+	})
+}
+
+// remoteSocket asks podman on the host for the path of its API socket
+func remoteSocket(ctx context.Context, client ssh.Client) (string, error) {
+	output, err := client.Execute(ctx, Command("info", "--format", "{{.Host.RemoteSocket.Path}}"))
+	if err != nil {
+		return "", fmt.Errorf("failed to find the podman socket: %w", err)
+	}
+	socket := strings.TrimPrefix(strings.TrimSpace(output), "unix://")
+	if socket == "" {
+		return "", errors.New("failed to find the podman socket; set podman_socket")
+	}
+	return socket, nil
+}
+
+// newAPIBackend returns an apiBackend whose connections are made by dial,
+// after checking that the API answers
+func newAPIBackend(ctx context.Context, dial func(ctx context.Context) (net.Conn, error)) (*apiBackend, error) {
+	b := &apiBackend{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dial(ctx)
+				},
+				DisableCompression: true,
+			},
+		},
+	}
+
+	version, err := b.ping(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.prefix = "/v" + version + "/libpod"
+	return b, nil
+}
+
+// ping checks that the API answers and returns its version
+func (b *apiBackend) ping(ctx context.Context) (string, error) {
+	resp, err := b.request(ctx, http.MethodGet, "/_ping", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if version := resp.Header.Get("Libpod-API-Version"); version != "" {
+		return version, nil
+	}
+	return defaultAPIVersion, nil
+}
+
+// request sends a request to path, which is not prefixed and already
+// escaped, and returns the response if its status is successful. Other
+// responses are returned as *APIError.
+func (b *apiBackend) request(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return nil, err
+	}
+	// Path alone would be escaped again, turning %2F into %252F
+	u := url.URL{Scheme: "http", Host: "d", Path: unescaped, RawPath: path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	var apiErr struct {
+		Cause   string `json:"cause"`
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return nil, &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+}
+
+// call sends a request to a libpod endpoint and decodes the JSON response
+// into out, unless out is nil
+func (b *apiBackend) call(ctx context.Context, method, path string, query url.Values, out any) error {
+	return b.send(ctx, method, path, query, nil, out)
+}
+
+// send is like call, with in encoded as the JSON body of the request unless
+// it is nil
+func (b *apiBackend) send(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := b.request(ctx, method, b.prefix+path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return nil
+}
+
+func (b *apiBackend) Ping(ctx context.Context) error {
+	_, err := b.ping(ctx)
+	return err
+}
+
+func (b *apiBackend) ListContainers(ctx context.Context) ([]Container, error) {
+	var containers []Container
+	err := b.call(ctx, http.MethodGet, "/containers/json", url.Values{"all": {"true"}}, &containers)
+	return containers, err
+}
+
+func (b *apiBackend) InspectContainer(ctx context.Context, nameOrID string) (*ContainerInspect, error) {
+	var raw json.RawMessage
+	if err := b.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(nameOrID)+"/json", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	var result ContainerInspect
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	result.Raw = raw
+	return &result, nil
+}
+
+func (b *apiBackend) StopContainer(ctx context.Context, nameOrID string) error {
+	// 304 Not Modified means the container was not running
+	return b.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(nameOrID)+"/stop", nil, nil)
+}
+
+func (b *apiBackend) RemoveContainer(ctx context.Context, nameOrID string) error {
+	resp, err := b.request(ctx, http.MethodDelete, b.prefix+"/containers/"+url.PathEscape(nameOrID), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Podman 4 and later answer with a report per removed container
+	var reports []pruneReport
+	if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&reports) == nil {
+		for _, r := range reports {
+			if r.Err != "" {
+				return errors.New(string(r.Err))
+			}
+		}
+	}
+	return nil
+}
+
+func (b *apiBackend) ListImages(ctx context.Context) ([]Image, error) {
+	var images []Image
+	err := b.call(ctx, http.MethodGet, "/images/json", nil, &images)
+	return images, err
+}
+
+func (b *apiBackend) InspectImage(ctx context.Context, ref string) (*Image, error) {
+	var image Image
+	if err := b.call(ctx, http.MethodGet, "/images/"+url.PathEscape(ref)+"/json", nil, &image); err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (b *apiBackend) PullImage(ctx context.Context, ref string, progress io.Writer) (string, error) {
+	if progress == nil {
+		progress = io.Discard
+	}
+	query := url.Values{"reference": {ref}, "policy": {"always"}}
+	resp, err := b.request(ctx, http.MethodPost, b.prefix+"/images/pull", query, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// The response is a stream of JSON messages; failures are reported in
+	// the stream after the status was sent
+	var id string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+			ID     string `json:"id"`
+		}
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		if msg.Stream != "" {
+			io.WriteString(progress, msg.Stream)
+		}
+		if msg.Error != "" {
+			return "", errors.New(msg.Error)
+		}
+		if msg.ID != "" {
+			id = msg.ID
+		}
+	}
+	if id == "" {
+		return "", errors.New("podman API: pull returned no image")
+	}
+	return id, nil
+}
+
+func (b *apiBackend) RemoveImage(ctx context.Context, ref string, force bool) error {
+	var report struct {
+		Errors []string `json:"Errors"`
+	}
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	if err := b.call(ctx, http.MethodDelete, "/images/"+url.PathEscape(ref), query, &report); err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return errors.New(strings.Join(report.Errors, "; "))
+	}
+	return nil
+}
+
+func (b *apiBackend) PruneImages(ctx context.Context, all bool, filters []string) ([]string, error) {
+	query, err := filterQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("all", strconv.FormatBool(all))
+
+	var reports []pruneReport
+	if err := b.call(ctx, http.MethodPost, "/images/prune", query, &reports); err != nil {
+		return nil, err
+	}
+	return pruned(reports)
+}
+
+func (b *apiBackend) ListVolumes(ctx context.Context) ([]Volume, error) {
+	var volumes []Volume
+	err := b.call(ctx, http.MethodGet, "/volumes/json", nil, &volumes)
+	return volumes, err
+}
+
+func (b *apiBackend) CreateVolume(ctx context.Context, opts VolumeCreateOptions) (string, error) {
+	// Podman before 4.0 only knows Label
+	in := map[string]any{
+		"Name":    opts.Name,
+		"Driver":  opts.Driver,
+		"Label":   opts.Labels,
+		"Labels":  opts.Labels,
+		"Options": opts.Options,
+	}
+	var volume Volume
+	if err := b.send(ctx, http.MethodPost, "/volumes/create", nil, in, &volume); err != nil {
+		return "", err
+	}
+	return volume.Name, nil
+}
+
+func (b *apiBackend) RemoveVolume(ctx context.Context, name string, force bool) error {
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	return b.call(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), query, nil)
+}
+
+func (b *apiBackend) InspectVolume(ctx context.Context, name string) (*Volume, error) {
+	var volume Volume
+	if err := b.call(ctx, http.MethodGet, "/volumes/"+url.PathEscape(name)+"/json", nil, &volume); err != nil {
+		return nil, err
+	}
+	return &volume, nil
+}
+
+func (b *apiBackend) ListNetworks(ctx context.Context) ([]Network, error) {
+	var networks []Network
+	err := b.call(ctx, http.MethodGet, "/networks/json", nil, &networks)
+	return networks, err
+}
+
+func (b *apiBackend) CreateNetwork(ctx context.Context, opts NetworkCreateOptions) (string, error) {
+	in := Network{Name: opts.Name, Driver: opts.Driver, Internal: opts.Internal, Labels: opts.Labels}
+	if opts.Subnet != "" {
+		in.Subnets = []Subnet{{Subnet: opts.Subnet, Gateway: opts.Gateway}}
+	}
+	var network Network
+	if err := b.send(ctx, http.MethodPost, "/networks/create", nil, in, &network); err != nil {
+		return "", err
+	}
+	return network.Name, nil
+}
+
+func (b *apiBackend) RemoveNetwork(ctx context.Context, name string, force bool) error {
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	var reports []struct {
+		Name string      `json:"Name"`
+		Err  reportError `json:"Err"`
+	}
+	if err := b.call(ctx, http.MethodDelete, "/networks/"+url.PathEscape(name), query, &reports); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		if r.Err != "" {
+			return errors.New(string(r.Err))
+		}
+	}
+	return nil
+}
+
+func (b *apiBackend) InspectNetwork(ctx context.Context, name string) (*Network, error) {
+	var network Network
+	if err := b.call(ctx, http.MethodGet, "/networks/"+url.PathEscape(name)+"/json", nil, &network); err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+func (b *apiBackend) ListPods(ctx context.Context) ([]Pod, error) {
+	var pods []Pod
+	err := b.call(ctx, http.MethodGet, "/pods/json", nil, &pods)
+	return pods, err
+}
+
+func (b *apiBackend) CreatePod(ctx context.Context, opts PodCreateOptions) (string, error) {
+	in := map[string]any{"name": opts.Name, "labels": opts.Labels}
+	var mappings []PortMapping
+	for _, spec := range opts.Publish {
+		mapping, err := parsePublish(spec)
+		if err != nil {
+			return "", err
+		}
+		mappings = append(mappings, mapping)
+	}
+	if len(mappings) > 0 {
+		in["portmappings"] = mappings
+	}
+	if len(opts.Networks) > 0 {
+		networks := map[string]any{}
+		for _, name := range opts.Networks {
+			networks[name] = struct{}{}
+		}
+		in["netns"] = map[string]string{"nsmode": "bridge"}
+		in["Networks"] = networks
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := b.send(ctx, http.MethodPost, "/pods/create", nil, in, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (b *apiBackend) StartPod(ctx context.Context, nameOrID string) error {
+	return b.podAction(ctx, nameOrID, "start")
+}
+
+func (b *apiBackend) StopPod(ctx context.Context, nameOrID string) error {
+	return b.podAction(ctx, nameOrID, "stop")
+}
+
+// podAction starts or stops a pod. Podman answers 304 if there is nothing
+// to do.
+func (b *apiBackend) podAction(ctx context.Context, nameOrID, action string) error {
+	var report struct {
+		Errs []reportError `json:"Errs"`
+	}
+	if err := b.call(ctx, http.MethodPost, "/pods/"+url.PathEscape(nameOrID)+"/"+action, nil, &report); err != nil {
+		return err
+	}
+	var errs []string
+	for _, e := range report.Errs {
+		if e != "" {
+			errs = append(errs, string(e))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (b *apiBackend) RemovePod(ctx context.Context, nameOrID string, force bool) error {
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	var report struct {
+		Err reportError `json:"Err"`
+	}
+	if err := b.call(ctx, http.MethodDelete, "/pods/"+url.PathEscape(nameOrID), query, &report); err != nil {
+		return err
+	}
+	if report.Err != "" {
+		return errors.New(string(report.Err))
+	}
+	return nil
+}
+
+func (b *apiBackend) InspectPod(ctx context.Context, nameOrID string) (*PodInspect, error) {
+	var raw json.RawMessage
+	if err := b.call(ctx, http.MethodGet, "/pods/"+url.PathEscape(nameOrID)+"/json", nil, &raw); err != nil {
+		return nil, err
+	}
+	return parsePodInspect(raw)
+}
+
+// pruneReport is the outcome of removing one object
+type pruneReport struct {
+	ID  string      `json:"Id"`
+	Err reportError `json:"Err"`
+}
+
+// reportError is the error of a report, which podman encodes as a string,
+// as null or, for some error types, as an empty object
+type reportError string
+
+func (e *reportError) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*e = reportError(s)
+	}
+	return nil
+}
+
+// pruned returns the IDs of the removed objects, or the errors of those
+// that could not be removed
+func pruned(reports []pruneReport) ([]string, error) {
+	var ids, errs []string
+	for _, r := range reports {
+		if r.Err != "" {
+			errs = append(errs, string(r.Err))
+			continue
+		}
+		ids = append(ids, r.ID)
+	}
+	if len(errs) > 0 {
+		return ids, errors.New(strings.Join(errs, "; "))
+	}
+	return ids, nil
+}
+
+// filterQuery encodes filters given as "key=value" as the filters parameter
+// of the API
+func filterQuery(filters []string) (url.Values, error) {
+	query := url.Values{}
+	if len(filters) == 0 {
+		return query, nil
+	}
+	parsed, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("filters", string(data))
+	return query, nil
+}
@@ -0,0 +1,321 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestAPIBackend serves handler on a local unix socket and returns an
+// apiBackend connected to it
+func newTestAPIBackend(t *testing.T, handler http.Handler) *apiBackend {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	backend, err := newAPIBackend(context.Background(), func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	})
+	if err != nil {
+		t.Fatalf("newAPIBackend should succeed, got: %v", err)
+	}
+	return backend
+}
+
+// testAPI returns a mux answering _ping as podman 5.2.0
+func testAPI() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Libpod-API-Version", "5.2.0")
+		w.Write([]byte("OK"))
+	})
+	return mux
+}
+
+// TestAPIBackend_ListContainers verifies the versioned path and decoding
+func TestAPIBackend_ListContainers(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "true" {
+			t.Errorf("expected all=true, got: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"Id": "0123456789abcdef", "Names": ["web"], "Created": "2023-11-14T22:13:20Z",
+			"Ports": [{"host_ip": "", "container_port": 80, "host_port": 8080, "range": 1, "protocol": "tcp"}]}]`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	containers, err := backend.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers should succeed, got: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Name() != "web" || containers[0].PortsString() != "0.0.0.0:8080->80/tcp" {
+		t.Errorf("unexpected containers: %+v", containers)
+	}
+}
+
+// TestAPIBackend_InspectContainer verifies that the raw JSON is kept
+func TestAPIBackend_InspectContainer(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/containers/web/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "abc", "Name": "web", "State": {"Running": true}, "Extra": 1}`))
+	})
+	mux.HandleFunc("/v5.2.0/libpod/containers/db/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"cause": "no such container", "message": "no container with name or ID \"db\" found: no such container", "response": 404}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	result, err := backend.InspectContainer(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("InspectContainer should succeed, got: %v", err)
+	}
+	if !result.State.Running || !strings.Contains(string(result.Raw), `"Extra"`) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	_, err = backend.InspectContainer(context.Background(), "db")
+	if !IsNotFound(err) || !strings.Contains(err.Error(), `no container with name or ID "db"`) {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
+
+// TestAPIBackend_RemoveContainer verifies that per-container errors are
+// reported
+func TestAPIBackend_RemoveContainer(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/containers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE, got: %s", r.Method)
+		}
+		if strings.HasSuffix(r.URL.Path, "/running") {
+			w.Write([]byte(`[{"Id": "abc", "Err": "container is running"}]`))
+			return
+		}
+		w.Write([]byte(`[{"Id": "abc", "Err": null}]`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	if err := backend.RemoveContainer(context.Background(), "stopped"); err != nil {
+		t.Errorf("RemoveContainer should succeed, got: %v", err)
+	}
+	if err := backend.RemoveContainer(context.Background(), "running"); err == nil || err.Error() != "container is running" {
+		t.Errorf("expected the report's error, got: %v", err)
+	}
+}
+
+// TestAPIBackend_InspectImage_Qualified verifies that a reference with a
+// registry and namespace is escaped once, as podman expects
+func TestAPIBackend_InspectImage_Qualified(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.EscapedPath(); got != "/v5.2.0/libpod/images/docker.io%2Flibrary%2Fnginx:latest/json" {
+			t.Errorf("unexpected path: %s", got)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "abcd", "RepoTags": ["docker.io/library/nginx:latest"]}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	image, err := backend.InspectImage(context.Background(), "docker.io/library/nginx:latest")
+	if err != nil || image.ID != "abcd" {
+		t.Errorf("expected image abcd, got: %+v, %v", image, err)
+	}
+}
+
+// TestNewBackend_Unknown verifies that unknown backend names are rejected
+func TestNewBackend_Unknown(t *testing.T) {
+	if _, err := NewBackend(context.Background(), "rest", nil, ""); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// TestAPIBackend_PullImage verifies that progress is streamed and errors in
+// the stream are reported
+func TestAPIBackend_PullImage(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("reference") == "missing" {
+			w.Write([]byte(`{"stream": "Trying to pull missing...\n"}{"error": "manifest unknown"}`))
+			return
+		}
+		w.Write([]byte(`{"stream": "Copying blob 1234\n"}{"images": ["abcd"], "id": "abcd"}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	var progress strings.Builder
+	id, err := backend.PullImage(context.Background(), "nginx", &progress)
+	if err != nil || id != "abcd" {
+		t.Errorf("expected image abcd, got: %q, %v", id, err)
+	}
+	if progress.String() != "Copying blob 1234\n" {
+		t.Errorf("unexpected progress: %q", progress.String())
+	}
+
+	if _, err := backend.PullImage(context.Background(), "missing", nil); err == nil || err.Error() != "manifest unknown" {
+		t.Errorf("expected the stream's error, got: %v", err)
+	}
+}
+
+// TestAPIBackend_RemoveImage_Qualified verifies removal of a reference with
+// a registry and namespace, as given to image rm
+func TestAPIBackend_RemoveImage_Qualified(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Query().Get("force") != "false" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		if got := r.URL.EscapedPath(); got != "/v5.2.0/libpod/images/docker.io%2Flibrary%2Fnginx" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cause": "image not known", "message": "docker.io/library/nginx: image not known", "response": 404}`))
+			return
+		}
+		w.Write([]byte(`{"Deleted": ["abcd"], "Untagged": ["docker.io/library/nginx:latest"], "Errors": [], "ExitCode": 0}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	if err := backend.RemoveImage(context.Background(), "docker.io/library/nginx", false); err != nil {
+		t.Errorf("RemoveImage should succeed, got: %v", err)
+	}
+}
+
+// TestAPIBackend_PruneImages verifies the filters parameter and reports
+func TestAPIBackend_PruneImages(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/images/prune", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("filters"); got != `{"until":["240h"]}` {
+			t.Errorf("unexpected filters: %s", got)
+		}
+		w.Write([]byte(`[{"Id": "aaaa", "Err": null, "Size": 10}, {"Id": "bbbb", "Size": 20}]`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	ids, err := backend.PruneImages(context.Background(), true, []string{"until=240h"})
+	if err != nil || !reflect.DeepEqual(ids, []string{"aaaa", "bbbb"}) {
+		t.Errorf("unexpected result: %v, %v", ids, err)
+	}
+	if _, err := backend.PruneImages(context.Background(), false, []string{"until"}); err == nil {
+		t.Error("expected an error for an invalid filter")
+	}
+}
+
+// TestAPIBackend_CreateVolume verifies the request body
+func TestAPIBackend_CreateVolume(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/volumes/create", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name    string
+			Labels  map[string]string
+			Options map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		if body.Name != "data" || body.Labels["app"] != "db" || body.Options["type"] != "tmpfs" {
+			t.Errorf("unexpected body: %+v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Name": "data", "Driver": "local"}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	name, err := backend.CreateVolume(context.Background(), VolumeCreateOptions{
+		Name:    "data",
+		Labels:  map[string]string{"app": "db"},
+		Options: map[string]string{"type": "tmpfs"},
+	})
+	if err != nil || name != "data" {
+		t.Errorf("expected volume data, got: %q, %v", name, err)
+	}
+}
+
+// TestAPIBackend_RemoveVolume verifies the force parameter and that the
+// empty response is accepted
+func TestAPIBackend_RemoveVolume(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/volumes/data", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Query().Get("force") != "true" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	if err := backend.RemoveVolume(context.Background(), "data", true); err != nil {
+		t.Errorf("RemoveVolume should succeed, got: %v", err)
+	}
+	if err := backend.RemoveVolume(context.Background(), "missing", false); !IsNotFound(err) {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
+
+// TestAPIBackend_CreatePod verifies the port mappings and networks sent
+func TestAPIBackend_CreatePod(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/pods/create", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name         string                     `json:"name"`
+			PortMappings []PortMapping              `json:"portmappings"`
+			NetNS        map[string]string          `json:"netns"`
+			Networks     map[string]json.RawMessage `json:"Networks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		want := PortMapping{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}
+		if body.Name != "frontend" || len(body.PortMappings) != 1 || body.PortMappings[0] != want {
+			t.Errorf("unexpected body: %+v", body)
+		}
+		if body.NetNS["nsmode"] != "bridge" || body.Networks["backend"] == nil {
+			t.Errorf("unexpected networks: %+v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "abcd"}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	id, err := backend.CreatePod(context.Background(), PodCreateOptions{
+		Name:     "frontend",
+		Publish:  []string{"8080:80"},
+		Networks: []string{"backend"},
+	})
+	if err != nil || id != "abcd" {
+		t.Errorf("expected pod abcd, got: %q, %v", id, err)
+	}
+	if _, err := backend.CreatePod(context.Background(), PodCreateOptions{Publish: []string{"http"}}); err == nil {
+		t.Error("expected an error for an invalid port mapping")
+	}
+}
+
+// TestAPIBackend_StartPod verifies that an already running pod is not an
+// error and that container errors are reported
+func TestAPIBackend_StartPod(t *testing.T) {
+	mux := testAPI()
+	mux.HandleFunc("/v5.2.0/libpod/pods/running/start", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})
+	mux.HandleFunc("/v5.2.0/libpod/pods/broken/start", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "abcd", "Errs": ["container web: exec failed", null]}`))
+	})
+	backend := newTestAPIBackend(t, mux)
+
+	if err := backend.StartPod(context.Background(), "running"); err != nil {
+		t.Errorf("StartPod should succeed, got: %v", err)
+	}
+	if err := backend.StartPod(context.Background(), "broken"); err == nil || err.Error() != "container web: exec failed" {
+		t.Errorf("expected the report's error, got: %v", err)
+	}
+}
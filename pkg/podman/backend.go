@@ -0,0 +1,112 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// Backend names, set per host with podman_backend
+const (
+	// BackendCLI runs the podman command over SSH and parses its output
+	BackendCLI = "cli"
+	// BackendAPI talks to the libpod REST API on podman's unix socket,
+	// tunnelled through the SSH connection
+	BackendAPI = "api"
+)
+
+// Backend performs podman operations on one host
+type Backend interface {
+	// Ping checks that podman is usable on the host
+	Ping(ctx context.Context) error
+	// ListContainers lists all containers, including stopped ones
+	ListContainers(ctx context.Context) ([]Container, error)
+	// InspectContainer returns the details of a container
+	InspectContainer(ctx context.Context, nameOrID string) (*ContainerInspect, error)
+	// StopContainer stops a running container
+	StopContainer(ctx context.Context, nameOrID string) error
+	// RemoveContainer removes a stopped container
+	RemoveContainer(ctx context.Context, nameOrID string) error
+
+	// ListImages lists all images
+	ListImages(ctx context.Context) ([]Image, error)
+	// InspectImage returns the details of an image
+	InspectImage(ctx context.Context, ref string) (*Image, error)
+	// PullImage pulls an image, writing progress messages to progress, and
+	// returns its ID
+	PullImage(ctx context.Context, ref string, progress io.Writer) (string, error)
+	// RemoveImage removes an image; with force, containers using it are
+	// removed too
+	RemoveImage(ctx context.Context, ref string, force bool) error
+	// PruneImages removes dangling images, or with all every image not used
+	// by a container, that match filters given as "key=value". It returns
+	// the IDs of the removed images.
+	PruneImages(ctx context.Context, all bool, filters []string) ([]string, error)
+
+	// ListVolumes lists all volumes
+	ListVolumes(ctx context.Context) ([]Volume, error)
+	// CreateVolume creates a volume and returns its name
+	CreateVolume(ctx context.Context, opts VolumeCreateOptions) (string, error)
+	// RemoveVolume removes a volume; with force, containers using it are
+	// removed too
+	RemoveVolume(ctx context.Context, name string, force bool) error
+	// InspectVolume returns the details of a volume
+	InspectVolume(ctx context.Context, name string) (*Volume, error)
+
+	// ListNetworks lists all networks
+	ListNetworks(ctx context.Context) ([]Network, error)
+	// CreateNetwork creates a network and returns its name
+	CreateNetwork(ctx context.Context, opts NetworkCreateOptions) (string, error)
+	// RemoveNetwork removes a network; with force, containers and pods
+	// using it are removed too
+	RemoveNetwork(ctx context.Context, name string, force bool) error
+	// InspectNetwork returns the details of a network
+	InspectNetwork(ctx context.Context, name string) (*Network, error)
+
+	// ListPods lists all pods
+	ListPods(ctx context.Context) ([]Pod, error)
+	// CreatePod creates a pod and returns its ID
+	CreatePod(ctx context.Context, opts PodCreateOptions) (string, error)
+	// StartPod starts all containers of a pod
+	StartPod(ctx context.Context, nameOrID string) error
+	// StopPod stops all containers of a pod
+	StopPod(ctx context.Context, nameOrID string) error
+	// RemovePod removes a pod; with force, running containers are stopped
+	// and removed first
+	RemovePod(ctx context.Context, nameOrID string, force bool) error
+	// InspectPod returns the details of a pod
+	InspectPod(ctx context.Context, nameOrID string) (*PodInspect, error)
+}
+
+// IsNotFound reports whether err means that a container, image or other
+// object does not exist, as reported by either backend
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) && exitErr.Kind == ssh.RemoteFailure {
+		stderr := strings.ToLower(exitErr.Stderr)
+		return strings.Contains(stderr, "no such") || strings.Contains(stderr, "not known")
+	}
+	return false
+}
+
+// NewBackend returns the backend named kind for a host reached through
+// client. An empty kind selects BackendCLI. socket is the path of podman's
+// API socket for BackendAPI; if empty, it is asked from podman on the host.
+func NewBackend(ctx context.Context, kind string, client ssh.Client, socket string) (Backend, error) {
+	switch kind {
+	case "", BackendCLI:
+		return NewCLIBackend(client), nil
+	case BackendAPI:
+		return NewAPIBackend(ctx, client, socket)
+	}
+	return nil, fmt.Errorf("unknown podman backend %q", kind)
+}
@@ -0,0 +1,293 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// cliBackend runs the podman command on the host
+type cliBackend struct {
+	client ssh.Client
+}
+
+// NewCLIBackend returns a Backend running the podman command over client
+func NewCLIBackend(client ssh.Client) Backend {
+	return &cliBackend{client: client}
+}
+
+func (b *cliBackend) Ping(ctx context.Context) error {
+	_, err := b.client.Execute(ctx, "podman info > /dev/null 2>&1")
+	return err
+}
+
+func (b *cliBackend) ListContainers(ctx context.Context) ([]Container, error) {
+	output, err := b.client.Execute(ctx, Command("ps", "-a", "--format", "json"))
+	if err != nil {
+		return nil, err
+	}
+	return parseContainers([]byte(output))
+}
+
+func (b *cliBackend) InspectContainer(ctx context.Context, nameOrID string) (*ContainerInspect, error) {
+	output, err := b.client.Execute(ctx, Command("inspect", "--type", "container", nameOrID))
+	if err != nil {
+		return nil, err
+	}
+	return parseInspect([]byte(output))
+}
+
+func (b *cliBackend) StopContainer(ctx context.Context, nameOrID string) error {
+	_, err := b.client.Execute(ctx, Command("stop", nameOrID))
+	return err
+}
+
+func (b *cliBackend) RemoveContainer(ctx context.Context, nameOrID string) error {
+	_, err := b.client.Execute(ctx, Command("rm", nameOrID))
+	return err
+}
+
+func (b *cliBackend) ListImages(ctx context.Context) ([]Image, error) {
+	output, err := b.client.Execute(ctx, Command("images", "--format", "json"))
+	if err != nil {
+		return nil, err
+	}
+	return parseImages([]byte(output))
+}
+
+func (b *cliBackend) InspectImage(ctx context.Context, ref string) (*Image, error) {
+	output, err := b.client.Execute(ctx, Command("image", "inspect", ref))
+	if err != nil {
+		return nil, err
+	}
+	images, err := parseImages([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image %s not found", ref)
+	}
+	return &images[0], nil
+}
+
+func (b *cliBackend) PullImage(ctx context.Context, ref string, progress io.Writer) (string, error) {
+	if progress == nil {
+		progress = io.Discard
+	}
+	var stdout bytes.Buffer
+	if err := b.client.Stream(ctx, Command("pull", ref), nil, &stdout, progress); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (b *cliBackend) RemoveImage(ctx context.Context, ref string, force bool) error {
+	args := []string{"rmi"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := b.client.Execute(ctx, Command(append(args, ref)...))
+	return err
+}
+
+func (b *cliBackend) PruneImages(ctx context.Context, all bool, filters []string) ([]string, error) {
+	if _, err := parseFilters(filters); err != nil {
+		return nil, err
+	}
+	args := []string{"image", "prune", "--force"}
+	if all {
+		args = append(args, "--all")
+	}
+	for _, f := range filters {
+		args = append(args, "--filter", f)
+	}
+	output, err := b.client.Execute(ctx, Command(args...))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+func (b *cliBackend) ListVolumes(ctx context.Context) ([]Volume, error) {
+	output, err := b.client.Execute(ctx, Command("volume", "ls", "--format", "json"))
+	if err != nil {
+		return nil, err
+	}
+	return parseVolumes([]byte(output))
+}
+
+func (b *cliBackend) CreateVolume(ctx context.Context, opts VolumeCreateOptions) (string, error) {
+	args := []string{"volume", "create"}
+	if opts.Driver != "" {
+		args = append(args, "--driver", opts.Driver)
+	}
+	for _, key := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", key+"="+opts.Labels[key])
+	}
+	for _, key := range sortedKeys(opts.Options) {
+		args = append(args, "--opt", key+"="+opts.Options[key])
+	}
+	if opts.Name != "" {
+		args = append(args, opts.Name)
+	}
+	output, err := b.client.Execute(ctx, Command(args...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (b *cliBackend) RemoveVolume(ctx context.Context, name string, force bool) error {
+	args := []string{"volume", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := b.client.Execute(ctx, Command(append(args, name)...))
+	return err
+}
+
+func (b *cliBackend) InspectVolume(ctx context.Context, name string) (*Volume, error) {
+	output, err := b.client.Execute(ctx, Command("volume", "inspect", name))
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := parseVolumes([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("volume %s not found", name)
+	}
+	return &volumes[0], nil
+}
+
+// sortedKeys returns the keys of m in order, so that commands are
+// reproducible
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (b *cliBackend) ListNetworks(ctx context.Context) ([]Network, error) {
+	output, err := b.client.Execute(ctx, Command("network", "ls", "--format", "json"))
+	if err != nil {
+		return nil, err
+	}
+	return parseNetworks([]byte(output))
+}
+
+func (b *cliBackend) CreateNetwork(ctx context.Context, opts NetworkCreateOptions) (string, error) {
+	args := []string{"network", "create"}
+	if opts.Driver != "" {
+		args = append(args, "--driver", opts.Driver)
+	}
+	if opts.Subnet != "" {
+		args = append(args, "--subnet", opts.Subnet)
+	}
+	if opts.Gateway != "" {
+		args = append(args, "--gateway", opts.Gateway)
+	}
+	if opts.Internal {
+		args = append(args, "--internal")
+	}
+	for _, key := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", key+"="+opts.Labels[key])
+	}
+	if opts.Name != "" {
+		args = append(args, opts.Name)
+	}
+	output, err := b.client.Execute(ctx, Command(args...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (b *cliBackend) RemoveNetwork(ctx context.Context, name string, force bool) error {
+	args := []string{"network", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := b.client.Execute(ctx, Command(append(args, name)...))
+	return err
+}
+
+func (b *cliBackend) InspectNetwork(ctx context.Context, name string) (*Network, error) {
+	output, err := b.client.Execute(ctx, Command("network", "inspect", name))
+	if err != nil {
+		return nil, err
+	}
+	networks, err := parseNetworks([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("network %s not found", name)
+	}
+	return &networks[0], nil
+}
+
+func (b *cliBackend) ListPods(ctx context.Context) ([]Pod, error) {
+	output, err := b.client.Execute(ctx, Command("pod", "ps", "--format", "json"))
+	if err != nil {
+		return nil, err
+	}
+	return parsePods([]byte(output))
+}
+
+func (b *cliBackend) CreatePod(ctx context.Context, opts PodCreateOptions) (string, error) {
+	args := []string{"pod", "create"}
+	for _, key := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", key+"="+opts.Labels[key])
+	}
+	for _, spec := range opts.Publish {
+		args = append(args, "--publish", spec)
+	}
+	for _, network := range opts.Networks {
+		args = append(args, "--network", network)
+	}
+	if opts.Name != "" {
+		args = append(args, "--name", opts.Name)
+	}
+	output, err := b.client.Execute(ctx, Command(args...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (b *cliBackend) StartPod(ctx context.Context, nameOrID string) error {
+	_, err := b.client.Execute(ctx, Command("pod", "start", nameOrID))
+	return err
+}
+
+func (b *cliBackend) StopPod(ctx context.Context, nameOrID string) error {
+	_, err := b.client.Execute(ctx, Command("pod", "stop", nameOrID))
+	return err
+}
+
+func (b *cliBackend) RemovePod(ctx context.Context, nameOrID string, force bool) error {
+	args := []string{"pod", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := b.client.Execute(ctx, Command(append(args, nameOrID)...))
+	return err
+}
+
+func (b *cliBackend) InspectPod(ctx context.Context, nameOrID string) (*PodInspect, error) {
+	output, err := b.client.Execute(ctx, Command("pod", "inspect", nameOrID))
+	if err != nil {
+		return nil, err
+	}
+	return parsePodInspect([]byte(output))
+}
@@ -0,0 +1,36 @@
+package podman
+
+import (
+	"regexp"
+	"strings"
+)
+
+// safeArg matches arguments that need no quoting in a POSIX shell
+var safeArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// Quote quotes arg for a POSIX shell so that it reaches the remote command
+// as a single, unmodified argument
+func Quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if safeArg.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// Join quotes every element of argv and joins them into a command line
+func Join(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Command builds a podman command line from its arguments, e.g.
+// Command("stop", name). Every argument is quoted.
+func Command(args ...string) string {
+	return Join(append([]string{"podman"}, args...))
+}
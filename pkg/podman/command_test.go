@@ -0,0 +1,77 @@
+package podman
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestQuote verifies quoting of individual arguments
+func TestQuote(t *testing.T) {
+	testCases := []struct {
+		arg  string
+		want string
+	}{
+		{"nginx:latest", "nginx:latest"},
+		{"--name=web", "--name=web"},
+		{"", "''"},
+		{"hello world", "'hello world'"},
+		{"x; rm -rf ~", "'x; rm -rf ~'"},
+		{"it's", `'it'"'"'s'`},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, tc := range testCases {
+		if got := Quote(tc.arg); got != tc.want {
+			t.Errorf("Quote(%q) = %s, want: %s", tc.arg, got, tc.want)
+		}
+	}
+}
+
+// TestCommand verifies that podman is prepended and every argument quoted
+func TestCommand(t *testing.T) {
+	got := Command("rm", "x; rm -rf ~")
+	if got != "podman rm 'x; rm -rf ~'" {
+		t.Errorf("unexpected command: %s", got)
+	}
+}
+
+// TestJoin_RoundTrip verifies that arguments reach a POSIX shell intact
+func TestJoin_RoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	args := []string{
+		"MSG=hello world",
+		`say "hi"`,
+		"it's",
+		"$HOME ${PATH}",
+		"`id`",
+		"$(id)",
+		"line1\nline2",
+		"a\\b",
+		"x; rm -rf ~",
+		"*",
+		"",
+		"-n",
+	}
+
+	// Print each argument followed by a NUL so that empty arguments and
+	// newlines are visible in the output
+	out, err := exec.Command(sh, "-c", "printf '%s\\0' "+Join(args)).Output()
+	if err != nil {
+		t.Fatalf("shell failed: %v", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(got) != len(args) {
+		t.Fatalf("expected %d arguments, got %d: %q", len(args), len(got), got)
+	}
+	for i := range args {
+		if got[i] != args[i] {
+			t.Errorf("argument %d: expected %q, got %q", i, args[i], got[i])
+		}
+	}
+}
@@ -1,91 +1,157 @@
 package podman
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/ytnobody/podman-swarm/pkg/ssh"
+	"strconv"
+	"strings"
 )
 
+// Container is a container as listed by podman ps --format json
 type Container struct {
-	ID      string
-	Name    string
-	Image   string
-	Status  string
-	Ports   string
-	Created string
+	ID         string            `json:"Id"`
+	Names      []string          `json:"Names"`
+	Image      string            `json:"Image"`
+	ImageID    string            `json:"ImageID"`
+	Command    []string          `json:"Command"`
+	Created    Timestamp         `json:"Created"`
+	StartedAt  Timestamp         `json:"StartedAt"`
+	ExitedAt   Timestamp         `json:"ExitedAt"`
+	Exited     bool              `json:"Exited"`
+	ExitCode   int               `json:"ExitCode"`
+	State      string            `json:"State"`
+	Status     string            `json:"Status"`
+	Labels     map[string]string `json:"Labels"`
+	Pod        string            `json:"Pod,omitempty"`
+	PodName    string            `json:"PodName,omitempty"`
+	Networks   []string          `json:"Networks"`
+	Mounts     []string          `json:"Mounts"`
+	Ports      []PortMapping     `json:"Ports"`
+	Size       *ContainerSize    `json:"Size,omitempty"`
+	Pid        int               `json:"Pid"`
+	Restarts   int               `json:"Restarts"`
+	IsInfra    bool              `json:"IsInfra"`
+	AutoRemove bool              `json:"AutoRemove"`
 }
 
-type ContainerListResult struct {
-	Hostname   string
-	Containers []Container
-	Error      string
+// Name returns the first name of the container
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return c.Names[0]
 }
 
-// ListContainers executes podman ps on a remote host
-func ListContainers(ctx context.Context, hostname string, client ssh.Client) (*ContainerListResult, error) {
-	result := &ContainerListResult{Hostname: hostname}
+// ShortID returns the first 12 characters of the container ID
+func (c Container) ShortID() string {
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
 
-	output, err := client.Execute(ctx, "podman ps -a --format json")
-	if err != nil {
-		result.Error = err.Error()
-		return result, nil
+// PortsString formats the port mappings like podman ps,
+// e.g. "0.0.0.0:8080->80/tcp"
+func (c Container) PortsString() string {
+	ports := make([]string, len(c.Ports))
+	for i, p := range c.Ports {
+		ports[i] = p.String()
 	}
+	return strings.Join(ports, ", ")
+}
+
+// PortMapping is a published port of a container
+type PortMapping struct {
+	HostIP        string `json:"host_ip"`
+	ContainerPort int    `json:"container_port"`
+	HostPort      int    `json:"host_port"`
+	// Range is the number of consecutive ports mapped, 1 if unset
+	Range    int    `json:"range"`
+	Protocol string `json:"protocol"`
+}
 
-	var containers []map[string]interface{}
-	if err := json.Unmarshal([]byte(output), &containers); err != nil {
-		result.Error = fmt.Sprintf("failed to parse JSON: %v", err)
-		return result, nil
+func (p *PortMapping) UnmarshalJSON(data []byte) error {
+	// Podman 3 used camel case names
+	var m struct {
+		HostIP        string `json:"host_ip"`
+		ContainerPort int    `json:"container_port"`
+		HostPort      int    `json:"host_port"`
+		Range         int    `json:"range"`
+		Protocol      string `json:"protocol"`
+		LegacyHostIP  string `json:"hostIP"`
+		LegacyCPort   int    `json:"containerPort"`
+		LegacyHPort   int    `json:"hostPort"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*p = PortMapping{HostIP: m.HostIP, ContainerPort: m.ContainerPort, HostPort: m.HostPort, Range: m.Range, Protocol: m.Protocol}
+	if p.HostIP == "" {
+		p.HostIP = m.LegacyHostIP
 	}
+	if p.ContainerPort == 0 {
+		p.ContainerPort = m.LegacyCPort
+	}
+	if p.HostPort == 0 {
+		p.HostPort = m.LegacyHPort
+	}
+	return nil
+}
 
-	for _, c := range containers {
-		container := Container{
-			ID:      toString(c["Id"]),
-			Name:    toString(c["Names"]),
-			Image:   toString(c["Image"]),
-			Status:  toString(c["Status"]),
-			Ports:   toString(c["Ports"]),
-			Created: toString(c["Created"]),
-		}
-		result.Containers = append(result.Containers, container)
+func (p PortMapping) String() string {
+	hostIP := p.HostIP
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "tcp"
 	}
+	hostPort, containerPort := strconv.Itoa(p.HostPort), strconv.Itoa(p.ContainerPort)
+	if p.Range > 1 {
+		hostPort += "-" + strconv.Itoa(p.HostPort+p.Range-1)
+		containerPort += "-" + strconv.Itoa(p.ContainerPort+p.Range-1)
+	}
+	return fmt.Sprintf("%s:%s->%s/%s", hostIP, hostPort, containerPort, protocol)
+}
 
-	return result, nil
+// ContainerSize is the disk usage of a container, listed with podman ps --size
+type ContainerSize struct {
+	RootFsSize int64 `json:"rootFsSize"`
+	RwSize     int64 `json:"rwSize"`
 }
 
-// InspectContainer executes podman inspect on a remote host
-func InspectContainer(ctx context.Context, hostname string, client ssh.Client, cid string) (map[string]interface{}, error) {
-	output, err := client.Execute(ctx, fmt.Sprintf("podman inspect %s", cid))
-	if err != nil {
-		return nil, err
-	}
+// ContainerListResult is the containers of one host, or why they could not
+// be listed
+type ContainerListResult struct {
+	Hostname   string
+	Containers []Container
+	Error      string
+}
 
-	var result []map[string]interface{}
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
+// parseContainers decodes the output of podman ps --format json
+func parseContainers(data []byte) ([]Container, error) {
+	var containers []Container
+	if err := json.Unmarshal(data, &containers); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
+	return containers, nil
+}
 
-	if len(result) == 0 {
+// parseInspect decodes the first container of the output of podman inspect
+func parseInspect(data []byte) (*ContainerInspect, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if len(raw) == 0 {
 		return nil, fmt.Errorf("container not found")
 	}
 
-	return result[0], nil
-}
-
-func toString(v interface{}) string {
-	if v == nil {
-		return ""
-	}
-	switch val := v.(type) {
-	case string:
-		return val
-	case []interface{}:
-		if len(val) > 0 {
-			return toString(val[0])
-		}
-		return ""
-	default:
-		return fmt.Sprintf("%v", v)
+	var result ContainerInspect
+	if err := json.Unmarshal(raw[0], &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
+	result.Raw = raw[0]
+	return &result, nil
 }
@@ -0,0 +1,131 @@
+package podman
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseContainers verifies decoding of podman 4 ps output
+func TestParseContainers(t *testing.T) {
+	data := `[{
+		"Id": "0123456789abcdef0123",
+		"Names": ["web", "web-alias"],
+		"Image": "docker.io/library/nginx:latest",
+		"Command": ["nginx", "-g", "daemon off;"],
+		"Created": 1700000000,
+		"StartedAt": 1700000010,
+		"ExitedAt": -62135596800,
+		"ExitCode": 0,
+		"State": "running",
+		"Status": "Up 5 minutes",
+		"Labels": {"app": "web"},
+		"Pod": "abc",
+		"PodName": "frontend",
+		"Networks": ["podman"],
+		"Ports": [
+			{"host_ip": "", "container_port": 80, "host_port": 8080, "range": 1, "protocol": "tcp"},
+			{"host_ip": "127.0.0.1", "container_port": 5000, "host_port": 6000, "range": 3, "protocol": "udp"}
+		]
+	}]`
+
+	containers, err := parseContainers([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("expected 1 container, got %d", len(containers))
+	}
+	c := containers[0]
+
+	if c.ShortID() != "0123456789ab" {
+		t.Errorf("unexpected short ID: %s", c.ShortID())
+	}
+	if c.Name() != "web" || len(c.Names) != 2 {
+		t.Errorf("unexpected names: %v", c.Names)
+	}
+	if c.Labels["app"] != "web" || c.PodName != "frontend" {
+		t.Errorf("unexpected labels or pod: %v %s", c.Labels, c.PodName)
+	}
+	if !c.Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected created time: %v", c.Created)
+	}
+	if !c.ExitedAt.IsZero() {
+		t.Errorf("expected zero exit time, got %v", c.ExitedAt)
+	}
+	if got := c.PortsString(); got != "0.0.0.0:8080->80/tcp, 127.0.0.1:6000-6002->5000-5002/udp" {
+		t.Errorf("unexpected ports: %s", got)
+	}
+}
+
+// TestParseContainers_Podman3 verifies decoding of podman 3 ps output
+func TestParseContainers_Podman3(t *testing.T) {
+	data := `[{
+		"Id": "abc",
+		"Names": ["db"],
+		"Created": "2023-11-14T22:13:20Z",
+		"Ports": [{"hostIP": "", "containerPort": 5432, "hostPort": 15432, "protocol": "tcp"}]
+	}]`
+
+	containers, err := parseContainers([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := containers[0]
+	if c.ShortID() != "abc" {
+		t.Errorf("unexpected short ID: %s", c.ShortID())
+	}
+	if !c.Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected created time: %v", c.Created)
+	}
+	if got := c.PortsString(); got != "0.0.0.0:15432->5432/tcp" {
+		t.Errorf("unexpected ports: %s", got)
+	}
+}
+
+// TestParseInspect verifies that typed fields are decoded and the raw JSON kept
+func TestParseInspect(t *testing.T) {
+	data := `[{
+		"Id": "0123456789abcdef",
+		"Name": "web",
+		"Created": "2023-11-14T22:13:20.123456789Z",
+		"State": {"Status": "running", "Running": true, "Pid": 42, "StartedAt": "2023-11-14T22:13:21Z", "FinishedAt": "0001-01-01T00:00:00Z"},
+		"Config": {"Labels": {"app": "web"}, "Env": ["A=1"]},
+		"NetworkSettings": {"Ports": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}]}},
+		"HostConfig": {"RestartPolicy": {"Name": "always"}},
+		"Unknown": {"kept": true}
+	}]`
+
+	result, err := parseInspect([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Name != "web" || !result.State.Running || result.State.Pid != 42 {
+		t.Errorf("unexpected state: %+v", result.State)
+	}
+	if !result.State.FinishedAt.IsZero() {
+		t.Errorf("expected zero finish time, got %v", result.State.FinishedAt)
+	}
+	if result.Config.Labels["app"] != "web" || result.HostConfig.RestartPolicy.Name != "always" {
+		t.Errorf("unexpected config: %+v %+v", result.Config, result.HostConfig)
+	}
+	if ports := result.NetworkSettings.Ports["80/tcp"]; len(ports) != 1 || ports[0].HostPort != "8080" {
+		t.Errorf("unexpected ports: %v", result.NetworkSettings.Ports)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"Unknown"`) {
+		t.Errorf("raw JSON not preserved: %s", encoded)
+	}
+}
+
+// TestParseInspect_NotFound verifies the error for an empty result
+func TestParseInspect_NotFound(t *testing.T) {
+	if _, err := parseInspect([]byte("[]")); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,79 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Image is an image as listed by podman images --format json
+type Image struct {
+	ID          string            `json:"Id"`
+	ParentID    string            `json:"ParentId"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Names       []string          `json:"Names"`
+	Digest      string            `json:"Digest"`
+	Created     Timestamp         `json:"Created"`
+	Size        int64             `json:"Size"`
+	SharedSize  int64             `json:"SharedSize"`
+	VirtualSize int64             `json:"VirtualSize"`
+	Labels      map[string]string `json:"Labels"`
+	Containers  int               `json:"Containers"`
+	Dangling    bool              `json:"Dangling"`
+	ReadOnly    bool              `json:"ReadOnly"`
+}
+
+// ShortID returns the first 12 characters of the image ID
+func (i Image) ShortID() string {
+	id := strings.TrimPrefix(i.ID, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// ImageListResult is the images of one host, or why they could not be
+// listed
+type ImageListResult struct {
+	Hostname string
+	Images   []Image
+	Error    string
+}
+
+// SplitReference splits an image reference into its repository and tag,
+// e.g. "docker.io/library/nginx" and "latest". The tag is empty if the
+// reference has none.
+func SplitReference(ref string) (repository, tag string) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	slash := strings.LastIndex(ref, "/")
+	if i := strings.LastIndex(ref, ":"); i > slash {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// parseImages decodes the output of podman images --format json
+func parseImages(data []byte) ([]Image, error) {
+	var images []Image
+	if err := json.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return images, nil
+}
+
+// parseFilters splits filters given as "key=value" for the API, grouping
+// the values of a key
+func parseFilters(filters []string) (map[string][]string, error) {
+	parsed := map[string][]string{}
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid filter %q (expected key=value)", f)
+		}
+		parsed[key] = append(parsed[key], value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,67 @@
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+// TestSplitReference verifies splitting of image references
+func TestSplitReference(t *testing.T) {
+	tests := []struct {
+		ref, repository, tag string
+	}{
+		{"nginx", "nginx", ""},
+		{"nginx:1.25", "nginx", "1.25"},
+		{"localhost:5000/app", "localhost:5000/app", ""},
+		{"localhost:5000/app:v2", "localhost:5000/app", "v2"},
+		{"quay.io/app@sha256:abcd", "quay.io/app", ""},
+	}
+	for _, tc := range tests {
+		repository, tag := SplitReference(tc.ref)
+		if repository != tc.repository || tag != tc.tag {
+			t.Errorf("SplitReference(%q) = %q, %q, want: %q, %q", tc.ref, repository, tag, tc.repository, tc.tag)
+		}
+	}
+}
+
+// TestArchiveImageID verifies reading the image ID from a docker-archive
+func TestArchiveImageID(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{"0123abcd.tar", "layer"},
+		{"manifest.json", `[{"Config": "4f3e2d1c.json", "RepoTags": ["app:latest"], "Layers": ["0123abcd.tar"]}]`},
+	}
+	for _, f := range files {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))})
+		tw.Write([]byte(f.content))
+	}
+	tw.Close()
+
+	id, err := ArchiveImageID(&buf)
+	if err != nil || id != "4f3e2d1c" {
+		t.Errorf("expected 4f3e2d1c, got: %q, %v", id, err)
+	}
+
+	if _, err := ArchiveImageID(bytes.NewReader(nil)); err == nil {
+		t.Error("expected an error for an empty archive")
+	}
+}
+
+// TestRegistry verifies the registry found in image references
+func TestRegistry(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                            "docker.io",
+		"library/nginx:latest":             "docker.io",
+		"docker.io/library/nginx":          "docker.io",
+		"quay.io/prometheus/node-exporter": "quay.io",
+		"registry.example.com:5000/app:1":  "registry.example.com:5000",
+		"localhost/app":                    "localhost",
+	}
+	for ref, want := range tests {
+		if got := Registry(ref); got != want {
+			t.Errorf("Registry(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
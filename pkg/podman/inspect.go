@@ -0,0 +1,123 @@
+package podman
+
+import "encoding/json"
+
+// ContainerInspect is the output of podman inspect for a container. Only
+// commonly used fields are decoded; Raw holds the complete JSON.
+type ContainerInspect struct {
+	ID              string                     `json:"Id"`
+	Name            string                     `json:"Name"`
+	Created         Timestamp                  `json:"Created"`
+	Path            string                     `json:"Path"`
+	Args            []string                   `json:"Args"`
+	State           ContainerState             `json:"State"`
+	Image           string                     `json:"Image"`
+	ImageName       string                     `json:"ImageName"`
+	Pod             string                     `json:"Pod"`
+	RestartCount    int                        `json:"RestartCount"`
+	Mounts          []InspectMount             `json:"Mounts"`
+	NetworkSettings InspectNetworkSettings     `json:"NetworkSettings"`
+	Config          InspectContainerConfig     `json:"Config"`
+	HostConfig      InspectContainerHostConfig `json:"HostConfig"`
+	IsInfra         bool                       `json:"IsInfra"`
+
+	// Raw is the JSON podman printed for the container
+	Raw json.RawMessage `json:"-"`
+}
+
+// MarshalJSON returns the JSON podman printed, so that no field is lost
+func (c ContainerInspect) MarshalJSON() ([]byte, error) {
+	if len(c.Raw) > 0 {
+		return c.Raw, nil
+	}
+	type plain ContainerInspect
+	return json.Marshal(plain(c))
+}
+
+// ContainerState is the runtime state of a container
+type ContainerState struct {
+	Status     string        `json:"Status"`
+	Running    bool          `json:"Running"`
+	Paused     bool          `json:"Paused"`
+	Restarting bool          `json:"Restarting"`
+	OOMKilled  bool          `json:"OOMKilled"`
+	Dead       bool          `json:"Dead"`
+	Pid        int           `json:"Pid"`
+	ExitCode   int           `json:"ExitCode"`
+	Error      string        `json:"Error"`
+	StartedAt  Timestamp     `json:"StartedAt"`
+	FinishedAt Timestamp     `json:"FinishedAt"`
+	Health     *HealthStatus `json:"Health,omitempty"`
+}
+
+// HealthStatus is the result of the health checks of a container
+type HealthStatus struct {
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+}
+
+// InspectMount is a mount of a container
+type InspectMount struct {
+	Type        string   `json:"Type"`
+	Name        string   `json:"Name,omitempty"`
+	Source      string   `json:"Source"`
+	Destination string   `json:"Destination"`
+	Driver      string   `json:"Driver"`
+	Mode        string   `json:"Mode"`
+	Options     []string `json:"Options"`
+	RW          bool     `json:"RW"`
+	Propagation string   `json:"Propagation"`
+}
+
+// InspectNetworkSettings describes the networking of a container
+type InspectNetworkSettings struct {
+	IPAddress  string                          `json:"IPAddress"`
+	Gateway    string                          `json:"Gateway"`
+	MacAddress string                          `json:"MacAddress"`
+	Ports      map[string][]InspectHostPort    `json:"Ports"`
+	Networks   map[string]InspectNetworkMember `json:"Networks"`
+}
+
+// InspectHostPort is a host address a container port is published on
+type InspectHostPort struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// InspectNetworkMember is the attachment of a container to a network
+type InspectNetworkMember struct {
+	NetworkID  string   `json:"NetworkID"`
+	IPAddress  string   `json:"IPAddress"`
+	Gateway    string   `json:"Gateway"`
+	MacAddress string   `json:"MacAddress"`
+	Aliases    []string `json:"Aliases"`
+}
+
+// InspectContainerConfig is the configuration a container was created with
+type InspectContainerConfig struct {
+	Hostname   string            `json:"Hostname"`
+	User       string            `json:"User"`
+	Env        []string          `json:"Env"`
+	Cmd        []string          `json:"Cmd"`
+	Image      string            `json:"Image"`
+	WorkingDir string            `json:"WorkingDir"`
+	Labels     map[string]string `json:"Labels"`
+	StopSignal json.RawMessage   `json:"StopSignal,omitempty"`
+}
+
+// InspectContainerHostConfig is the host-specific configuration of a
+// container
+type InspectContainerHostConfig struct {
+	NetworkMode   string               `json:"NetworkMode"`
+	RestartPolicy InspectRestartPolicy `json:"RestartPolicy"`
+	Memory        int64                `json:"Memory"`
+	NanoCPUs      int64                `json:"NanoCpus"`
+	AutoRemove    bool                 `json:"AutoRemove"`
+	Privileged    bool                 `json:"Privileged"`
+}
+
+// InspectRestartPolicy is the restart policy of a container
+type InspectRestartPolicy struct {
+	Name              string `json:"Name"`
+	MaximumRetryCount uint   `json:"MaximumRetryCount"`
+}
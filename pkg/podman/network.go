@@ -0,0 +1,76 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Network is a network as listed by podman network ls --format json
+type Network struct {
+	Name             string            `json:"name"`
+	ID               string            `json:"id"`
+	Driver           string            `json:"driver"`
+	NetworkInterface string            `json:"network_interface,omitempty"`
+	Created          Timestamp         `json:"created"`
+	Subnets          []Subnet          `json:"subnets,omitempty"`
+	IPv6Enabled      bool              `json:"ipv6_enabled"`
+	Internal         bool              `json:"internal"`
+	DNSEnabled       bool              `json:"dns_enabled"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Options          map[string]string `json:"options,omitempty"`
+}
+
+// Subnet is an address range of a network
+type Subnet struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// ShortID returns the first 12 characters of the network ID
+func (n Network) ShortID() string {
+	if len(n.ID) > 12 {
+		return n.ID[:12]
+	}
+	return n.ID
+}
+
+// SubnetsString formats the subnets, e.g. "10.89.0.0/24, fd00::/64"
+func (n Network) SubnetsString() string {
+	subnets := make([]string, len(n.Subnets))
+	for i, s := range n.Subnets {
+		subnets[i] = s.Subnet
+	}
+	return strings.Join(subnets, ", ")
+}
+
+// NetworkCreateOptions are the settings of a new network
+type NetworkCreateOptions struct {
+	Name string
+	// Driver is bridge, macvlan or ipvlan; empty means bridge
+	Driver string
+	// Subnet in CIDR notation, allocated automatically if empty
+	Subnet string
+	// Gateway of the subnet, the first address if empty
+	Gateway  string
+	Internal bool
+	Labels   map[string]string
+}
+
+// NetworkListResult is the networks of one host, or why they could not be
+// listed
+type NetworkListResult struct {
+	Hostname string
+	Networks []Network
+	Error    string
+}
+
+// parseNetworks decodes the output of podman network ls --format json or
+// podman network inspect
+func parseNetworks(data []byte) ([]Network, error) {
+	var networks []Network
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return networks, nil
+}
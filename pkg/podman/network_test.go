@@ -0,0 +1,34 @@
+package podman
+
+import "testing"
+
+// TestParseNetworks verifies decoding of podman network ls output
+func TestParseNetworks(t *testing.T) {
+	data := `[{
+		"name": "backend",
+		"id": "0123456789abcdef0123",
+		"driver": "bridge",
+		"network_interface": "podman1",
+		"created": "2023-11-14T22:13:20.123456789Z",
+		"subnets": [{"subnet": "10.89.0.0/24", "gateway": "10.89.0.1"}, {"subnet": "fd00::/64"}],
+		"ipv6_enabled": true,
+		"internal": false,
+		"dns_enabled": true,
+		"ipam_options": {"driver": "host-local"}
+	}]`
+
+	networks, err := parseNetworks([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(networks) != 1 {
+		t.Fatalf("expected 1 network, got %d", len(networks))
+	}
+	n := networks[0]
+	if n.Name != "backend" || n.ShortID() != "0123456789ab" || !n.DNSEnabled {
+		t.Errorf("unexpected network: %+v", n)
+	}
+	if got := n.SubnetsString(); got != "10.89.0.0/24, fd00::/64" {
+		t.Errorf("unexpected subnets: %s", got)
+	}
+}
@@ -0,0 +1,149 @@
+package podman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pod is a pod as listed by podman pod ps --format json
+type Pod struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Status     string            `json:"Status"`
+	Created    Timestamp         `json:"Created"`
+	InfraID    string            `json:"InfraId"`
+	Labels     map[string]string `json:"Labels"`
+	Networks   []string          `json:"Networks"`
+	Containers []PodContainer    `json:"Containers"`
+}
+
+// PodContainer is a container of a listed pod
+type PodContainer struct {
+	ID     string `json:"Id"`
+	Names  string `json:"Names"`
+	Status string `json:"Status"`
+}
+
+// ShortID returns the first 12 characters of the pod ID
+func (p Pod) ShortID() string {
+	if len(p.ID) > 12 {
+		return p.ID[:12]
+	}
+	return p.ID
+}
+
+// PodInspect is the output of podman pod inspect. Only commonly used fields
+// are decoded; Raw holds the complete JSON.
+type PodInspect struct {
+	ID               string            `json:"Id"`
+	Name             string            `json:"Name"`
+	Created          Timestamp         `json:"Created"`
+	State            string            `json:"State"`
+	InfraContainerID string            `json:"InfraContainerID"`
+	NumContainers    int               `json:"NumContainers"`
+	Labels           map[string]string `json:"Labels"`
+	Containers       []struct {
+		ID    string `json:"Id"`
+		Name  string `json:"Name"`
+		State string `json:"State"`
+	} `json:"Containers"`
+
+	// Raw is the JSON podman printed for the pod
+	Raw json.RawMessage `json:"-"`
+}
+
+// MarshalJSON returns the JSON podman printed, so that no field is lost
+func (p PodInspect) MarshalJSON() ([]byte, error) {
+	if len(p.Raw) > 0 {
+		return p.Raw, nil
+	}
+	type plain PodInspect
+	return json.Marshal(plain(p))
+}
+
+// PodCreateOptions are the settings of a new pod
+type PodCreateOptions struct {
+	Name   string
+	Labels map[string]string
+	// Publish are port mappings as given to podman pod create --publish,
+	// e.g. "8080:80" or "127.0.0.1:5432:5432/tcp"
+	Publish []string
+	// Networks the pod joins instead of the default network
+	Networks []string
+}
+
+// PodListResult is the pods of one host, or why they could not be listed
+type PodListResult struct {
+	Hostname string
+	Pods     []Pod
+	Error    string
+}
+
+// parsePods decodes the output of podman pod ps --format json
+func parsePods(data []byte) ([]Pod, error) {
+	var pods []Pod
+	if err := json.Unmarshal(data, &pods); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return pods, nil
+}
+
+// parsePodInspect decodes the output of podman pod inspect, which is an
+// object before podman 5 and an array since
+func parsePodInspect(data []byte) (*PodInspect, error) {
+	raw := json.RawMessage(bytes.TrimSpace(data))
+	if bytes.HasPrefix(raw, []byte("[")) {
+		var pods []json.RawMessage
+		if err := json.Unmarshal(raw, &pods); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		if len(pods) == 0 {
+			return nil, fmt.Errorf("pod not found")
+		}
+		raw = pods[0]
+	}
+
+	var result PodInspect
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	result.Raw = raw
+	return &result, nil
+}
+
+// parsePublish converts a --publish value,
+// [[hostIP:][hostPort]:]containerPort[/protocol], to a port mapping
+func parsePublish(spec string) (PortMapping, error) {
+	mapping := PortMapping{Protocol: "tcp"}
+	ports, protocol, ok := strings.Cut(spec, "/")
+	if ok {
+		mapping.Protocol = protocol
+	}
+
+	var hostPort, containerPort string
+	parts := strings.Split(ports, ":")
+	switch len(parts) {
+	case 1:
+		containerPort = parts[0]
+	case 2:
+		hostPort, containerPort = parts[0], parts[1]
+	case 3:
+		mapping.HostIP, hostPort, containerPort = parts[0], parts[1], parts[2]
+	default:
+		return PortMapping{}, fmt.Errorf("invalid port mapping %q", spec)
+	}
+
+	var err error
+	if mapping.ContainerPort, err = strconv.Atoi(containerPort); err != nil {
+		return PortMapping{}, fmt.Errorf("invalid port mapping %q", spec)
+	}
+	if hostPort != "" {
+		if mapping.HostPort, err = strconv.Atoi(hostPort); err != nil {
+			return PortMapping{}, fmt.Errorf("invalid port mapping %q", spec)
+		}
+	}
+	return mapping, nil
+}
@@ -0,0 +1,80 @@
+package podman
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParsePods verifies decoding of podman pod ps output
+func TestParsePods(t *testing.T) {
+	data := `[{
+		"Cgroup": "machine.slice",
+		"Containers": [
+			{"Id": "aaaa", "Names": "frontend-infra", "Status": "running"},
+			{"Id": "bbbb", "Names": "web", "Status": "running"}
+		],
+		"Created": "2023-11-14T22:13:20Z",
+		"Id": "0123456789abcdef",
+		"InfraId": "aaaa",
+		"Name": "frontend",
+		"Networks": ["podman"],
+		"Status": "Running",
+		"Labels": {"app": "web"}
+	}]`
+
+	pods, err := parsePods([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected 1 pod, got %d", len(pods))
+	}
+	p := pods[0]
+	if p.ShortID() != "0123456789ab" || p.Name != "frontend" || len(p.Containers) != 2 || p.Containers[1].Names != "web" {
+		t.Errorf("unexpected pod: %+v", p)
+	}
+}
+
+// TestParsePodInspect verifies decoding of the object printed before podman
+// 5 and the array printed since
+func TestParsePodInspect(t *testing.T) {
+	object := `{"Id": "abc", "Name": "frontend", "State": "Running", "NumContainers": 2, "Extra": 1}`
+	for _, data := range []string{object, "[" + object + "]\n"} {
+		result, err := parsePodInspect([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Name != "frontend" || result.NumContainers != 2 || !strings.Contains(string(result.Raw), `"Extra"`) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	}
+
+	if _, err := parsePodInspect([]byte("[]")); err == nil {
+		t.Error("expected an error for an empty result")
+	}
+}
+
+// TestParsePublish verifies conversion of --publish values
+func TestParsePublish(t *testing.T) {
+	tests := []struct {
+		spec string
+		want PortMapping
+	}{
+		{"80", PortMapping{ContainerPort: 80, Protocol: "tcp"}},
+		{"8080:80", PortMapping{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		{"127.0.0.1:5353:53/udp", PortMapping{HostIP: "127.0.0.1", HostPort: 5353, ContainerPort: 53, Protocol: "udp"}},
+		{"127.0.0.1::80", PortMapping{HostIP: "127.0.0.1", ContainerPort: 80, Protocol: "tcp"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePublish(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("parsePublish(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"http", "8080-8081:80-81", "a:b:c:d"} {
+		if _, err := parsePublish(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
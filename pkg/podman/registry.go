@@ -0,0 +1,33 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// Registry returns the registry of an image reference, e.g. "quay.io" for
+// "quay.io/prometheus/node-exporter". Unqualified references are taken to
+// name docker.io.
+func Registry(ref string) string {
+	first, _, ok := strings.Cut(ref, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
+
+// Login logs podman on the host in to registry, so that later pulls by the
+// podman command or the API are authenticated. It always runs the podman
+// command; the password is passed on stdin.
+func Login(ctx context.Context, client ssh.Client, registry, username, password string) error {
+	var stderr bytes.Buffer
+	cmd := Command("login", "--username", username, "--password-stdin", registry)
+	if err := client.Stream(ctx, cmd, strings.NewReader(password), io.Discard, &stderr); err != nil {
+		return withStderr(err, &stderr)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// Image transfers always run the podman command, whatever the backend of
+// the host, so that the archive can be compressed on the host itself.
+
+// SaveImage writes ref as a docker-archive tarball to w, compressed with
+// zstd on the host if compress is set
+func SaveImage(ctx context.Context, client ssh.Client, ref string, compress bool, w io.Writer) error {
+	cmd := Command("save", "--format", "docker-archive", ref)
+	if compress {
+		cmd = pipeline(cmd, "zstd -c -q")
+	}
+	var stderr bytes.Buffer
+	if err := client.Stream(ctx, cmd, nil, w, &stderr); err != nil {
+		return withStderr(err, &stderr)
+	}
+	return nil
+}
+
+// LoadImage loads an image archive read from r, decompressing it with zstd
+// on the host if compressed is set. It returns the output of podman load,
+// e.g. "Loaded image: docker.io/library/nginx:latest".
+func LoadImage(ctx context.Context, client ssh.Client, r io.Reader, compressed bool) (string, error) {
+	cmd := Command("load")
+	if compressed {
+		cmd = pipeline("zstd -d -c -q", cmd)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := client.Stream(ctx, cmd, r, &stdout, &stderr); err != nil {
+		return "", withStderr(err, &stderr)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// pipeline joins commands with pipes into one that fails if any of them
+// fails, not only the last. It runs in bash, as the login shell may not
+// support pipefail.
+func pipeline(commands ...string) string {
+	return Join([]string{"bash", "-c", "set -o pipefail; " + strings.Join(commands, " | ")})
+}
+
+// withStderr adds the error output of a streamed command to its error
+func withStderr(err error, stderr *bytes.Buffer) error {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) && exitErr.Stderr == "" {
+		exitErr.Stderr = stderr.String()
+	}
+	return err
+}
+
+// ArchiveImageID returns the ID of the image in a docker-archive tarball,
+// as named by the configuration listed in its manifest.json
+func ArchiveImageID(r io.Reader) (string, error) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return "", errors.New("not a docker-archive: manifest.json not found")
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read archive: %w", err)
+		}
+		if path.Clean(header.Name) != "manifest.json" {
+			continue
+		}
+
+		var manifest []struct {
+			Config string `json:"Config"`
+		}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return "", fmt.Errorf("failed to parse manifest.json: %w", err)
+		}
+		if len(manifest) != 1 {
+			return "", fmt.Errorf("archive holds %d images, expected 1", len(manifest))
+		}
+		id := strings.TrimSuffix(path.Base(manifest[0].Config), ".json")
+		return strings.TrimPrefix(id, "sha256:"), nil
+	}
+}
+
+// ExportVolume writes the contents of a volume as a tarball to w
+func ExportVolume(ctx context.Context, client ssh.Client, name string, w io.Writer) error {
+	var stderr bytes.Buffer
+	if err := client.Stream(ctx, Command("volume", "export", name), nil, w, &stderr); err != nil {
+		return withStderr(err, &stderr)
+	}
+	return nil
+}
+
+// ImportVolume extracts a tarball read from r into a volume, creating the
+// volume if it does not exist
+func ImportVolume(ctx context.Context, client ssh.Client, name string, r io.Reader) error {
+	cmd := fmt.Sprintf("{ %s || %s > /dev/null; } && %s",
+		Command("volume", "exists", name), Command("volume", "create", name), Command("volume", "import", name, "-"))
+	var stderr bytes.Buffer
+	if err := client.Stream(ctx, cmd, r, io.Discard, &stderr); err != nil {
+		return withStderr(err, &stderr)
+	}
+	return nil
+}
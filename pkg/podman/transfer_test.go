@@ -0,0 +1,71 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ytnobody/podman-swarm/pkg/ssh"
+)
+
+// localClient runs commands with the local shell, standing in for a host
+type localClient struct{}
+
+func (localClient) Execute(ctx context.Context, cmd string) (string, error) {
+	var stdout bytes.Buffer
+	err := localClient{}.Stream(ctx, cmd, nil, &stdout, io.Discard)
+	return stdout.String(), err
+}
+
+func (localClient) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdin, c.Stdout, c.Stderr = stdin, stdout, stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ssh.ExitError{Kind: ssh.RemoteFailure, Command: cmd, ExitCode: exitErr.ExitCode(), Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (localClient) Interactive(ctx context.Context, cmd string, opts ssh.InteractiveOptions) error {
+	return localClient{}.Stream(ctx, cmd, opts.Stdin, opts.Stdout, opts.Stderr)
+}
+
+func (localClient) Close() error { return nil }
+
+// TestSaveImage_Compressed_SaveFails verifies that a failing podman save is
+// not hidden by zstd succeeding
+func TestSaveImage_Compressed_SaveFails(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	bin := t.TempDir()
+	scripts := map[string]string{
+		"podman": "#!/bin/sh\necho partial\necho 'Error: bad.example/app: image not known' >&2\nexit 125\n",
+		"zstd":   "#!/bin/sh\ncat\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	err := SaveImage(context.Background(), localClient{}, "bad.example/app", true, &stdout)
+	var exitErr *ssh.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 125 {
+		t.Fatalf("expected podman's exit status, got: %v", err)
+	}
+	if !IsNotFound(err) {
+		t.Errorf("expected podman's error output, got: %q", exitErr.Stderr)
+	}
+}
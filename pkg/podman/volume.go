@@ -0,0 +1,47 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Volume is a volume as listed by podman volume ls --format json
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	CreatedAt  Timestamp         `json:"CreatedAt"`
+	Labels     map[string]string `json:"Labels"`
+	Scope      string            `json:"Scope"`
+	Options    map[string]string `json:"Options"`
+	MountCount uint              `json:"MountCount"`
+	Anonymous  bool              `json:"Anonymous,omitempty"`
+}
+
+// VolumeCreateOptions are the settings of a new volume
+type VolumeCreateOptions struct {
+	// Name of the volume, generated if empty
+	Name   string
+	Driver string
+	Labels map[string]string
+	// Options are driver specific options, e.g. type=tmpfs
+	Options map[string]string
+}
+
+// VolumeListResult is the volumes of one host, or why they could not be
+// listed
+type VolumeListResult struct {
+	Hostname string
+	Volumes  []Volume
+	Error    string
+}
+
+// parseVolumes decodes the output of podman volume ls --format json or
+// podman volume inspect
+func parseVolumes(data []byte) ([]Volume, error) {
+	var volumes []Volume
+	if err := json.Unmarshal(data, &volumes); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return volumes, nil
+}
@@ -0,0 +1,36 @@
+package podman
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseVolumes verifies decoding of podman volume ls output
+func TestParseVolumes(t *testing.T) {
+	data := `[{
+		"Name": "data",
+		"Driver": "local",
+		"Mountpoint": "/var/lib/containers/storage/volumes/data/_data",
+		"CreatedAt": "2023-11-14T22:13:20Z",
+		"Labels": {"app": "db"},
+		"Scope": "local",
+		"Options": {},
+		"MountCount": 1,
+		"NeedsCopyUp": true
+	}]`
+
+	volumes, err := parseVolumes([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(volumes) != 1 {
+		t.Fatalf("expected 1 volume, got %d", len(volumes))
+	}
+	v := volumes[0]
+	if v.Name != "data" || v.Driver != "local" || v.Labels["app"] != "db" || v.MountCount != 1 {
+		t.Errorf("unexpected volume: %+v", v)
+	}
+	if !v.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected created time: %v", v.CreatedAt)
+	}
+}
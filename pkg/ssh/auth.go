@@ -0,0 +1,295 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
+)
+
+// Authentication sources accepted in ClientConfig.AuthOrder
+const (
+	AuthAgent       = "agent"
+	AuthCertificate = "certificate"
+	AuthKey         = "key"
+)
+
+// DefaultAuthOrder is the order in which authentication sources are offered
+// to the server when ClientConfig.AuthOrder is empty
+var DefaultAuthOrder = []string{AuthAgent, AuthCertificate, AuthKey}
+
+var (
+	// keyCacheMu serializes passphrase prompts and guards keyCache, so that a
+	// key shared by many hosts is decrypted only once per invocation.
+	keyCacheMu sync.Mutex
+	keyCache   = map[string]ssh.Signer{}
+)
+
+// authMethods builds a single public key auth method that offers the signers
+// of every configured source in order. The server only lets a client try the
+// "publickey" method once, so all signers have to go through one callback.
+func authMethods(config ClientConfig) ([]ssh.AuthMethod, func(), error) {
+	order := config.AuthOrder
+	if len(order) == 0 {
+		order = DefaultAuthOrder
+	}
+
+	var (
+		signers  []ssh.Signer
+		problems []string
+		agentErr error
+		agentSet []ssh.Signer
+	)
+
+	useAgent := false
+	for _, source := range order {
+		if source == AuthAgent {
+			useAgent = true
+		}
+	}
+
+	closeAgent := func() {}
+	agentSigners := func() ([]ssh.Signer, error) {
+		if !useAgent {
+			return nil, nil
+		}
+		if agentSet != nil || agentErr != nil {
+			return agentSet, agentErr
+		}
+		var closer func()
+		agentSet, closer, agentErr = loadAgentSigners()
+		if closer != nil {
+			closeAgent = closer
+		}
+		if agentSet == nil && agentErr == nil {
+			agentSet = []ssh.Signer{}
+		}
+		return agentSet, agentErr
+	}
+
+	for _, source := range order {
+		switch source {
+		case AuthAgent:
+			s, err := agentSigners()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("agent: %v", err))
+				continue
+			}
+			signers = append(signers, s...)
+
+		case AuthCertificate:
+			s, err := certificateSigner(config, agentSigners)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("certificate: %v", err))
+				continue
+			}
+			if s != nil {
+				signers = append(signers, s)
+			}
+
+		case AuthKey:
+			if config.PrivateKey == "" {
+				continue
+			}
+			if offeredByAgent(config, agentSigners) {
+				continue
+			}
+			s, err := privateKeySigner(config)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("key: %v", err))
+				continue
+			}
+			signers = append(signers, s)
+
+		default:
+			problems = append(problems, fmt.Sprintf("unknown auth method %q", source))
+		}
+	}
+
+	if len(signers) == 0 {
+		closeAgent()
+		if len(problems) == 0 {
+			return nil, nil, errors.New("no authentication methods available: configure private_key, certificate or ssh-agent")
+		}
+		return nil, nil, fmt.Errorf("no authentication methods available: %s", strings.Join(problems, "; "))
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, closeAgent, nil
+}
+
+// loadAgentSigners returns the keys held by the agent at SSH_AUTH_SOCK.
+// The returned function closes the agent connection, which must stay open
+// until authentication is finished.
+func loadAgentSigners() ([]ssh.Signer, func(), error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	return signers, func() { conn.Close() }, nil
+}
+
+// certificateSigner pairs the OpenSSH user certificate with its private key,
+// taken from the agent or from PrivateKey. It returns nil if no certificate
+// is configured and none exists next to the private key.
+func certificateSigner(config ClientConfig, agentSigners func() ([]ssh.Signer, error)) (ssh.Signer, error) {
+	path := config.Certificate
+	if path == "" {
+		if config.PrivateKey == "" {
+			return nil, nil
+		}
+		path = config.PrivateKey + "-cert.pub"
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an OpenSSH certificate", path)
+	}
+
+	if fromAgent, _ := agentSigners(); len(fromAgent) > 0 {
+		for _, s := range fromAgent {
+			if bytes.Equal(s.PublicKey().Marshal(), cert.Key.Marshal()) {
+				return ssh.NewCertSigner(cert, s)
+			}
+		}
+	}
+
+	if config.PrivateKey == "" {
+		return nil, fmt.Errorf("no private key for certificate %s in ssh-agent or private_key", path)
+	}
+	key, err := privateKeySigner(config)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewCertSigner(cert, key)
+}
+
+// offeredByAgent reports whether the agent already holds PrivateKey, in which
+// case there is no need to decrypt it (and prompt for its passphrase).
+func offeredByAgent(config ClientConfig, agentSigners func() ([]ssh.Signer, error)) bool {
+	pub, err := os.ReadFile(config.PrivateKey + ".pub")
+	if err != nil {
+		return false
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(pub)
+	if err != nil {
+		return false
+	}
+
+	fromAgent, err := agentSigners()
+	if err != nil {
+		return false
+	}
+	for _, s := range fromAgent {
+		if bytes.Equal(s.PublicKey().Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateKeySigner loads PrivateKey, decrypting it if it is passphrase
+// protected
+func privateKeySigner(config ClientConfig) (ssh.Signer, error) {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+
+	if signer, ok := keyCache[config.PrivateKey]; ok {
+		return signer, nil
+	}
+
+	key, err := os.ReadFile(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: open %s: %w", config.PrivateKey, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase, perr := keyPassphrase(config)
+		if perr != nil {
+			return nil, perr
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	keyCache[config.PrivateKey] = signer
+	return signer, nil
+}
+
+// keyPassphrase obtains the passphrase of an encrypted private key from
+// Passphrase, PassphraseCommand, PassphraseEnv or, as a last resort, the
+// terminal.
+func keyPassphrase(config ClientConfig) ([]byte, error) {
+	if config.Passphrase != nil {
+		passphrase, err := config.Passphrase()
+		if err != nil {
+			return nil, fmt.Errorf("passphrase for %s: %w", config.PrivateKey, err)
+		}
+		return passphrase, nil
+	}
+
+	if config.PassphraseCommand != "" {
+		out, err := exec.Command("sh", "-c", config.PassphraseCommand).Output()
+		if err != nil {
+			return nil, fmt.Errorf("passphrase_command for %s failed: %w", config.PrivateKey, err)
+		}
+		return bytes.TrimRight(out, "\r\n"), nil
+	}
+
+	if config.PassphraseEnv != "" {
+		if value, ok := os.LookupEnv(config.PassphraseEnv); ok {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("private key %s is encrypted but $%s is not set", config.PrivateKey, config.PassphraseEnv)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil || !term.IsTerminal(int(tty.Fd())) {
+		if tty != nil {
+			tty.Close()
+		}
+		return nil, fmt.Errorf("private key %s is encrypted; load it into ssh-agent or set passphrase_env or passphrase_command", config.PrivateKey)
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Enter passphrase for %s: ", config.PrivateKey)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
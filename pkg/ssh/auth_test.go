@@ -0,0 +1,136 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeTestKey writes an ed25519 private key, encrypted if passphrase is set,
+// and returns its path and public key
+func writeTestKey(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "test", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "test")
+	}
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return path, sshPub
+}
+
+func resetKeyCache() {
+	keyCacheMu.Lock()
+	keyCache = map[string]ssh.Signer{}
+	keyCacheMu.Unlock()
+}
+
+// TestPrivateKeySigner_PassphraseEnv verifies that encrypted keys are
+// decrypted with the passphrase from the configured environment variable
+func TestPrivateKeySigner_PassphraseEnv(t *testing.T) {
+	resetKeyCache()
+	path, pub := writeTestKey(t, "s3cret")
+	t.Setenv("TEST_KEY_PASSPHRASE", "s3cret")
+
+	signer, err := privateKeySigner(ClientConfig{PrivateKey: path, PassphraseEnv: "TEST_KEY_PASSPHRASE"})
+	if err != nil {
+		t.Fatalf("privateKeySigner should not fail: %v", err)
+	}
+	if ssh.FingerprintSHA256(signer.PublicKey()) != ssh.FingerprintSHA256(pub) {
+		t.Error("decrypted key does not match the generated key")
+	}
+}
+
+// TestPrivateKeySigner_PassphraseCommand verifies passphrase_command
+func TestPrivateKeySigner_PassphraseCommand(t *testing.T) {
+	resetKeyCache()
+	path, _ := writeTestKey(t, "s3cret")
+
+	if _, err := privateKeySigner(ClientConfig{PrivateKey: path, PassphraseCommand: "echo s3cret"}); err != nil {
+		t.Fatalf("privateKeySigner should not fail: %v", err)
+	}
+
+	resetKeyCache()
+	if _, err := privateKeySigner(ClientConfig{PrivateKey: path, PassphraseCommand: "echo wrong"}); err == nil {
+		t.Error("privateKeySigner should fail with a wrong passphrase")
+	}
+}
+
+// TestAuthMethods_Certificate verifies that a certificate next to the
+// private key is picked up
+func TestAuthMethods_Certificate(t *testing.T) {
+	resetKeyCache()
+	t.Setenv("SSH_AUTH_SOCK", "")
+	path, pub := writeTestKey(t, "")
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA signer: %v", err)
+	}
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"ubuntu"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatalf("failed to sign certificate: %v", err)
+	}
+	if err := os.WriteFile(path+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+
+	signer, err := certificateSigner(ClientConfig{PrivateKey: path}, func() ([]ssh.Signer, error) { return nil, nil })
+	if err != nil {
+		t.Fatalf("certificateSigner should not fail: %v", err)
+	}
+	if _, ok := signer.PublicKey().(*ssh.Certificate); !ok {
+		t.Errorf("expected a certificate signer, got: %T", signer.PublicKey())
+	}
+
+	if _, _, err := authMethods(ClientConfig{PrivateKey: path, AuthOrder: []string{AuthCertificate}}); err != nil {
+		t.Errorf("authMethods should not fail: %v", err)
+	}
+}
+
+// TestAuthMethods_NoneAvailable verifies that a missing agent and key are
+// reported together
+func TestAuthMethods_NoneAvailable(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, _, err := authMethods(ClientConfig{PrivateKey: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Fatal("authMethods should fail without any usable source")
+	}
+	if !strings.Contains(err.Error(), "agent:") || !strings.Contains(err.Error(), "key:") {
+		t.Errorf("error should mention every failed source, got: %v", err)
+	}
+}
@@ -3,15 +3,23 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
 type Client interface {
 	Execute(ctx context.Context, cmd string) (string, error)
+	// Stream runs cmd, feeding it stdin (which may be nil) and copying its
+	// output to stdout and stderr as it is produced
+	Stream(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error
+	// Interactive runs cmd attached to local streams, optionally on a
+	// pseudo-terminal, forwarding window size changes and signals
+	Interactive(ctx context.Context, cmd string, opts InteractiveOptions) error
 	Close() error
 }
 
@@ -20,53 +28,166 @@ type ClientConfig struct {
 	Port       int
 	Username   string
 	PrivateKey string
+
+	// HostKey pins the expected host key in authorized_keys format. When set,
+	// known_hosts is not consulted.
+	HostKey string
+	// KnownHosts is the known_hosts file used to verify the host key.
+	// Defaults to ~/.ssh/known_hosts.
+	KnownHosts string
+	// TrustOnFirstUse records the key of a host missing from KnownHosts
+	// instead of rejecting the connection. Changed keys are always rejected.
+	TrustOnFirstUse bool
+
+	// Certificate is an OpenSSH user certificate for PrivateKey or for a key
+	// held by ssh-agent. Defaults to PrivateKey + "-cert.pub" if it exists.
+	Certificate string
+	// PassphraseEnv names an environment variable holding the passphrase of
+	// an encrypted PrivateKey.
+	PassphraseEnv string
+	// PassphraseCommand is a shell command printing the passphrase of an
+	// encrypted PrivateKey.
+	PassphraseCommand string
+	// Passphrase obtains the passphrase of an encrypted PrivateKey. It takes
+	// precedence over PassphraseCommand and PassphraseEnv.
+	Passphrase func() ([]byte, error)
+	// AuthOrder lists the authentication sources to offer, in order.
+	// Defaults to DefaultAuthOrder.
+	AuthOrder []string
+
+	// Jump lists the bastion hosts to tunnel through, in order. The first
+	// hop is dialed directly. Connections to hops are shared between clients
+	// using the same chain.
+	Jump []ClientConfig
+
+	// Timeout bounds connecting to and authenticating with each hop. Zero
+	// means no limit other than the context's.
+	Timeout time.Duration
 }
 
+// killGracePeriod is how long a command may take to exit after being
+// signalled on cancellation before its session is closed
+const killGracePeriod = 2 * time.Second
+
 type sshClient struct {
-	client *ssh.Client
+	client  *ssh.Client
+	host    string
+	release func()
 }
 
-// NewClient creates a new SSH client
-func NewClient(config ClientConfig) (Client, error) {
+// NewClient creates a new SSH client. ctx bounds connecting only.
+func NewClient(ctx context.Context, config ClientConfig) (Client, error) {
+	client, release, err := connectClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sshClient{
+		client:  client,
+		host:    hostAddr(config),
+		release: release,
+	}, nil
+}
+
+// connectClient dials config.Host, through its jump chain if any. The
+// returned function releases the jump chain after the client is closed.
+func connectClient(ctx context.Context, config ClientConfig) (*ssh.Client, func(), error) {
 	if config.Port == 0 {
 		config.Port = 22
 	}
 
-	key, err := ioutil.ReadFile(config.PrivateKey)
+	var via *ssh.Client
+	release := func() {}
+	if len(config.Jump) > 0 {
+		var err error
+		via, release, err = acquireJumpChain(ctx, config.Jump)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client, err := dial(ctx, config, via)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read private key: open %s: %w", config.PrivateKey, err)
+		release()
+		return nil, nil, err
 	}
+	return client, release, nil
+}
 
-	signer, err := ssh.ParsePrivateKey(key)
+func hostAddr(config ClientConfig) string {
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+	return fmt.Sprintf("%s:%d", config.Host, port)
+}
+
+// dial connects and authenticates to config.Host, tunnelling through via
+// when it is not nil. The handshake is abandoned when ctx ends or
+// config.Timeout passes.
+func dial(ctx context.Context, config ClientConfig, via *ssh.Client) (*ssh.Client, error) {
+	verifyHostKey, err := hostKeyCallback(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+		return nil, err
 	}
 
+	auth, closeAgent, err := authMethods(config)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAgent()
+
 	sshConfig := &ssh.ClientConfig{
-		User: config.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil // TODO: Implement proper host key verification
-		},
+		User:              config.Username,
+		Auth:              auth,
+		HostKeyCallback:   verifyHostKey,
+		HostKeyAlgorithms: hostKeyAlgorithms(config),
+		Timeout:           config.Timeout,
+	}
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
 	}
 
 	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	var conn net.Conn
+	if via == nil {
+		var dialer net.Dialer
+		if conn, err = dialer.DialContext(ctx, "tcp", addr); err != nil {
+			return nil, fmt.Errorf("failed to dial: %w", err)
+		}
+	} else {
+		if conn, err = via.DialContext(ctx, "tcp", addr); err != nil {
+			return nil, fmt.Errorf("failed to dial through jump host: %w", err)
+		}
+	}
+
+	// The handshake does not take a context; closing the connection is the
+	// only way to interrupt it
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if !stop() {
+		if err == nil {
+			c.Close()
+		}
+		return nil, fmt.Errorf("failed to dial: %w", ctx.Err())
+	}
 	if err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to dial: %w", err)
 	}
-
-	return &sshClient{
-		client: client,
-	}, nil
+	return ssh.NewClient(c, chans, reqs), nil
 }
 
+// Execute runs cmd in a new session and returns its stdout. When ctx ends
+// before the command finishes, the remote process is signalled and the
+// session closed. Failures are reported as *ExitError.
 func (c *sshClient) Execute(ctx context.Context, cmd string) (string, error) {
-	session, err := c.client.NewSession()
+	session, err := c.newSession(ctx, cmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", err
 	}
 	defer session.Close()
 
@@ -74,16 +195,95 @@ func (c *sshClient) Execute(ctx context.Context, cmd string) (string, error) {
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	if err := session.Run(cmd); err != nil {
-		return "", fmt.Errorf("command failed: %v, stderr: %s", err, stderr.String())
+	if err := c.run(ctx, session, cmd); err != nil {
+		err.Stdout = stdout.String()
+		err.Stderr = stderr.String()
+		return "", err
 	}
 
 	return stdout.String(), nil
 }
 
-func (c *sshClient) Close() error {
-	if c.client != nil {
-		return c.client.Close()
+// Stream runs cmd in a new session without buffering its output. When ctx
+// ends before the command finishes, the remote process is signalled and the
+// session closed. Failures are reported as *ExitError.
+func (c *sshClient) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	session, err := c.newSession(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if err := c.run(ctx, session, cmd); err != nil {
+		return err
 	}
 	return nil
 }
+
+// newSession opens a session for cmd unless ctx has already ended
+func (c *sshClient) newSession(ctx context.Context, cmd string) (*ssh.Session, *ExitError) {
+	if ctx.Err() != nil {
+		return nil, contextError(ctx, c.host, cmd, "", "")
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1,
+			Err: fmt.Errorf("failed to create session: %w", err), sessionFailed: true}
+	}
+	return session, nil
+}
+
+// run starts cmd on session and waits for it, interrupting it when ctx ends
+func (c *sshClient) run(ctx context.Context, session *ssh.Session, cmd string) *ExitError {
+	if err := session.Start(cmd); err != nil {
+		return &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1,
+			Err: fmt.Errorf("failed to start command: %w", err)}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(killGracePeriod):
+			session.Close()
+			// Wait copies output until the channel is closed; the caller
+			// may only read its buffers after that
+			<-done
+		}
+		return contextError(ctx, c.host, cmd, "", "")
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Kind: RemoteFailure, Host: c.host, Command: cmd, ExitCode: exitErr.ExitStatus(), Err: err}
+	}
+	return &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1, Err: err}
+}
+
+func (c *sshClient) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	err := c.client.Close()
+	if c.release != nil {
+		c.release()
+	}
+	return err
+}
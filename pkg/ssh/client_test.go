@@ -1,7 +1,16 @@
 package ssh
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 // TestExecuteMultipleCalls verifies that Execute can be called multiple times
@@ -21,3 +30,300 @@ func TestExecuteMultipleCalls(t *testing.T) {
 	}
 }
 
+// TestExecute_ExitError verifies that a failing command is reported with its
+// exit code and output
+func TestExecute_ExitError(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		s.Channel.Write([]byte("partial"))
+		s.Channel.Stderr().Write([]byte("no such container"))
+		return 125
+	})
+
+	_, err := client.Execute(context.Background(), "podman stop web")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError, got: %v", err)
+	}
+	if exitErr.Kind != RemoteFailure || exitErr.ExitCode != 125 {
+		t.Errorf("expected remote failure with exit code 125, got: %v %d", exitErr.Kind, exitErr.ExitCode)
+	}
+	if exitErr.Stdout != "partial" || exitErr.Stderr != "no such container" {
+		t.Errorf("output mismatch, stdout: %q, stderr: %q", exitErr.Stdout, exitErr.Stderr)
+	}
+	if exitErr.Command != "podman stop web" {
+		t.Errorf("command mismatch, got: %s", exitErr.Command)
+	}
+}
+
+// TestExecute_Timeout verifies that a hung command is interrupted when the
+// context deadline passes
+func TestExecute_Timeout(t *testing.T) {
+	signalled := make(chan string, 1)
+	client := newTestServer(t, func(s *testSession) uint32 {
+		sig, ok := <-s.Signals
+		if ok {
+			signalled <- sig
+		}
+		return 143
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Execute(ctx, "podman ps")
+	if time.Since(start) > killGracePeriod+time.Second {
+		t.Errorf("Execute should return shortly after the deadline, took %v", time.Since(start))
+	}
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Kind != Timeout {
+		t.Fatalf("expected timeout ExitError, got: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("timeout error should wrap context.DeadlineExceeded")
+	}
+
+	select {
+	case sig := <-signalled:
+		if sig != "TERM" {
+			t.Errorf("expected TERM signal, got: %s", sig)
+		}
+	case <-time.After(time.Second):
+		t.Error("remote command should have been signalled")
+	}
+}
+
+// TestExecute_TimeoutWhileWriting verifies that output written until the
+// session is closed does not race with the returned error (run with -race)
+func TestExecute_TimeoutWhileWriting(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		for {
+			if _, err := s.Channel.Write([]byte("line\n")); err != nil {
+				return 143
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Execute(ctx, "podman logs -f web")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Kind != Timeout {
+		t.Fatalf("expected timeout ExitError, got: %v", err)
+	}
+	if !strings.HasPrefix(exitErr.Stdout, "line\n") {
+		t.Errorf("expected the output before the timeout, got: %q", exitErr.Stdout)
+	}
+}
+
+// TestExecute_Success verifies that stdout is returned for successful commands
+func TestExecute_Success(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		s.Channel.Write([]byte(s.Command))
+		return 0
+	})
+
+	for i := 0; i < 3; i++ {
+		output, err := client.Execute(context.Background(), "podman info")
+		if err != nil {
+			t.Fatalf("Execute should not fail: %v", err)
+		}
+		if output != "podman info" {
+			t.Errorf("output mismatch, got: %q", output)
+		}
+	}
+}
+
+// TestStream verifies that stdin is forwarded and stdout and stderr are kept
+// apart
+func TestStream(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		input, _ := io.ReadAll(s.Channel)
+		s.Channel.Write(bytes.ToUpper(input))
+		s.Channel.Stderr().Write([]byte("loaded"))
+		return 0
+	})
+
+	var stdout, stderr bytes.Buffer
+	err := client.Stream(context.Background(), "podman load", strings.NewReader("image data"), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Stream should not fail: %v", err)
+	}
+	if stdout.String() != "IMAGE DATA" {
+		t.Errorf("stdout mismatch, got: %q", stdout.String())
+	}
+	if stderr.String() != "loaded" {
+		t.Errorf("stderr mismatch, got: %q", stderr.String())
+	}
+}
+
+// TestStream_ExitError verifies that Stream reports remote failures
+func TestStream_ExitError(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		return 2
+	})
+
+	err := client.Stream(context.Background(), "false", nil, io.Discard, io.Discard)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 2 {
+		t.Errorf("expected ExitError with exit code 2, got: %v", err)
+	}
+}
+
+// TestInteractive_ReturnsWhileStdinBlocked verifies that Interactive returns
+// the remote exit status without waiting for stdin to be closed
+func TestInteractive_ReturnsWhileStdinBlocked(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 {
+		buf := make([]byte, 5)
+		io.ReadFull(s.Channel, buf)
+		s.Channel.Write(buf)
+		return 7
+	})
+
+	stdinReader, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	go stdinWriter.Write([]byte("hello"))
+
+	sizes := make(chan WindowSize, 1)
+	sizes <- WindowSize{Width: 120, Height: 40}
+
+	var stdout bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Interactive(context.Background(), "podman exec -it web sh", InteractiveOptions{
+			Stdin:    stdinReader,
+			Stdout:   &stdout,
+			Stderr:   io.Discard,
+			Terminal: &Terminal{Term: "xterm", Size: WindowSize{Width: 80, Height: 24}, Resize: sizes},
+		})
+	}()
+
+	select {
+	case err := <-done:
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode != 7 {
+			t.Errorf("expected ExitError with exit code 7, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Interactive should return when the remote command exits")
+	}
+
+	if stdout.String() != "hello" {
+		t.Errorf("stdout mismatch, got: %q", stdout.String())
+	}
+}
+
+// TestDialSocket verifies tunnelling to a unix socket on the remote host
+func TestDialSocket(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 { return 0 })
+
+	conn, err := client.DialSocket(context.Background(), testSocketPath)
+	if err != nil {
+		t.Fatalf("DialSocket should succeed, got: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("expected echo, got: %q, %v", buf, err)
+	}
+}
+
+// TestDialSocket_Rejected verifies that a missing socket is reported without
+// treating the connection as broken
+func TestDialSocket_Rejected(t *testing.T) {
+	client := newTestServer(t, func(s *testSession) uint32 { return 0 })
+
+	_, err := client.DialSocket(context.Background(), "/run/missing.sock")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected ExitError, got: %v", err)
+	}
+	if exitErr.sessionFailed {
+		t.Error("a rejected dial should not mark the connection broken")
+	}
+	if !strings.Contains(err.Error(), "no such file or directory") {
+		t.Errorf("expected the server's reason, got: %v", err)
+	}
+}
+
+// startSilentServer accepts TCP connections but never starts the SSH
+// handshake, like a host whose sshd hangs. It returns the listening port.
+func startSilentServer(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// TestConnectClient_HandshakeTimeout verifies that connecting to a host that
+// never answers gives up when the context or the configured timeout ends,
+// including when the host is a jump hop
+func TestConnectClient_HandshakeTimeout(t *testing.T) {
+	keyPath, _ := writeTestKey(t, "")
+	silent := ClientConfig{
+		Host:       "127.0.0.1",
+		Port:       startSilentServer(t),
+		Username:   "test",
+		PrivateKey: keyPath,
+		HostKey:    string(ssh.MarshalAuthorizedKey(newTestHostKey(t))),
+		AuthOrder:  []string{AuthKey},
+	}
+	withTimeout := silent
+	withTimeout.Timeout = 100 * time.Millisecond
+	target := silent
+	target.Port = 2222
+	target.Jump = []ClientConfig{withTimeout}
+
+	tests := []struct {
+		name    string
+		config  ClientConfig
+		timeout time.Duration
+	}{
+		{name: "context", config: silent, timeout: 100 * time.Millisecond},
+		{name: "config", config: withTimeout},
+		{name: "jump host", config: target},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			_, _, err := connectClient(ctx, tt.config)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected deadline exceeded, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("connecting should give up after the timeout, took %v", elapsed)
+			}
+		})
+	}
+}
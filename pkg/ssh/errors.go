@@ -0,0 +1,93 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorKind tells why a remote command did not succeed
+type ErrorKind int
+
+const (
+	// RemoteFailure means the command ran and exited with a non-zero status
+	RemoteFailure ErrorKind = iota
+	// Timeout means the context deadline passed before the command finished
+	Timeout
+	// Canceled means the context was canceled before the command finished
+	Canceled
+	// ConnectionFailure means the session could not be opened or was lost
+	// before the command reported an exit status
+	ConnectionFailure
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case RemoteFailure:
+		return "remote failure"
+	case Timeout:
+		return "timeout"
+	case Canceled:
+		return "canceled"
+	case ConnectionFailure:
+		return "connection failure"
+	}
+	return "unknown"
+}
+
+// ExitError is returned by Client.Execute when a command does not complete
+// successfully. Use errors.As to inspect it.
+type ExitError struct {
+	Kind    ErrorKind
+	Host    string
+	Command string
+	// ExitCode is the remote exit status, or -1 if the command did not exit
+	// normally
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Err      error
+
+	// sessionFailed is set when no session could be opened, i.e. the
+	// command never ran and may be retried on a new connection
+	sessionFailed bool
+}
+
+func (e *ExitError) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	switch e.Kind {
+	case RemoteFailure:
+		if stderr == "" {
+			return fmt.Sprintf("command failed: exit status %d", e.ExitCode)
+		}
+		return fmt.Sprintf("command failed: exit status %d, stderr: %s", e.ExitCode, stderr)
+	case Timeout:
+		return fmt.Sprintf("command timed out on %s", e.Host)
+	case Canceled:
+		return fmt.Sprintf("command canceled on %s", e.Host)
+	default:
+		return fmt.Sprintf("connection to %s failed: %v", e.Host, e.Err)
+	}
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// contextError builds the ExitError for a command interrupted by ctx
+func contextError(ctx context.Context, host, cmd string, stdout, stderr string) *ExitError {
+	kind := Canceled
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		kind = Timeout
+	}
+	return &ExitError{
+		Kind:     kind,
+		Host:     host,
+		Command:  cmd,
+		ExitCode: -1,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Err:      ctx.Err(),
+	}
+}
@@ -0,0 +1,264 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// UnknownHostKeyError is returned when a host presents a key that is not
+// present in known_hosts and trust-on-first-use is disabled, or a key of a
+// type not recorded for a known host.
+type UnknownHostKeyError struct {
+	Host        string
+	Fingerprint string
+	KnownHosts  string
+	// KeyType is the type of the presented key, set when keys of other
+	// types (Recorded) are known for the host
+	KeyType  string
+	Recorded []string
+}
+
+func (e *UnknownHostKeyError) Error() string {
+	if len(e.Recorded) > 0 {
+		return fmt.Sprintf("unknown key type %s for %s (%s): %s only records %s keys for it; run 'podman-swarm hostkeys scan' to trust it",
+			e.KeyType, e.Host, e.Fingerprint, e.KnownHosts, strings.Join(e.Recorded, ", "))
+	}
+	return fmt.Sprintf("host key for %s (%s) is not in %s; run 'podman-swarm hostkeys scan' to trust it",
+		e.Host, e.Fingerprint, e.KnownHosts)
+}
+
+// HostKeyMismatchError is returned when a host presents a key that differs
+// from the one recorded for it.
+type HostKeyMismatchError struct {
+	Host        string
+	Fingerprint string
+	Want        []string
+	Source      string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch for %s: got %s, expected %s from %s (possible man-in-the-middle attack; if the key was legitimately changed, run 'podman-swarm hostkeys forget' and scan again)",
+		e.Host, e.Fingerprint, strings.Join(e.Want, ", "), e.Source)
+}
+
+// knownHostsMu serializes trust-on-first-use writes to known_hosts files
+// from concurrent connections.
+var knownHostsMu sync.Mutex
+
+// DefaultKnownHostsFile returns the path of the user's known_hosts file
+func DefaultKnownHostsFile() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ssh", "known_hosts")
+}
+
+func hostKeyCallback(config ClientConfig) (ssh.HostKeyCallback, error) {
+	if config.HostKey != "" {
+		return pinnedHostKeyCallback(config.HostKey)
+	}
+
+	path := config.KnownHosts
+	if path == "" {
+		path = DefaultKnownHostsFile()
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if !config.TrustOnFirstUse {
+			return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+				return &UnknownHostKeyError{Host: hostname, Fingerprint: ssh.FingerprintSHA256(key), KnownHosts: path}
+			}, nil
+		}
+		if err := createKnownHostsFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts %s: %w", path, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		// knownhosts reports a key of another type than the recorded ones
+		// as a mismatch; only a different key of the same type is one
+		var want, recorded []string
+		for _, k := range keyErr.Want {
+			if k.Key.Type() == key.Type() {
+				want = append(want, fmt.Sprintf("%s (%s:%d)", ssh.FingerprintSHA256(k.Key), k.Filename, k.Line))
+			}
+			recorded = append(recorded, k.Key.Type())
+		}
+		if len(want) > 0 {
+			return &HostKeyMismatchError{
+				Host:        hostname,
+				Fingerprint: ssh.FingerprintSHA256(key),
+				Want:        want,
+				Source:      path,
+			}
+		}
+
+		if !config.TrustOnFirstUse || len(recorded) > 0 {
+			unknown := &UnknownHostKeyError{Host: hostname, Fingerprint: ssh.FingerprintSHA256(key), KnownHosts: path}
+			if len(recorded) > 0 {
+				sort.Strings(recorded)
+				unknown.KeyType, unknown.Recorded = key.Type(), recorded
+			}
+			return unknown
+		}
+		_, err = appendKnownHost(path, hostname, key)
+		return err
+	}, nil
+}
+
+// hostKeyAlgorithms returns the host key algorithms for the key types known
+// for config.Host, so that a host with several keys presents a recorded one
+// rather than the type it prefers. It returns nil, accepting any algorithm,
+// when no plain key is recorded.
+func hostKeyAlgorithms(config ClientConfig) []string {
+	if config.HostKey != "" {
+		want, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.HostKey))
+		if err != nil {
+			return nil
+		}
+		return keyAlgorithms(want.Type())
+	}
+
+	path := config.KnownHosts
+	if path == "" {
+		path = DefaultKnownHostsFile()
+	}
+	entries, err := LookupKnownHost(path, config.Host, config.Port)
+	if err != nil {
+		return nil
+	}
+
+	var algorithms []string
+	seen := map[string]bool{}
+	for _, e := range entries {
+		switch e.Marker {
+		case "":
+		case "@cert-authority":
+			// Any certificate algorithm may be signed by the authority
+			return nil
+		default:
+			continue
+		}
+		for _, algorithm := range keyAlgorithms(e.KeyType) {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// keyAlgorithms returns the signature algorithms a host key of keyType can
+// be presented with
+func keyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+func pinnedHostKeyCallback(hostKey string) (ssh.HostKeyCallback, error) {
+	want, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host_key: %w", err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if key.Type() == want.Type() && bytes.Equal(key.Marshal(), want.Marshal()) {
+			return nil
+		}
+		return &HostKeyMismatchError{
+			Host:        hostname,
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Want:        []string{ssh.FingerprintSHA256(want)},
+			Source:      "host_key",
+		}
+	}, nil
+}
+
+func createKnownHostsFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create known_hosts: %w", err)
+	}
+	return f.Close()
+}
+
+// appendKnownHost records key for hostname ("host:port") in the known_hosts
+// file at path. The file is checked again under the lock, as another
+// connection may have recorded the host since the caller looked: it reports
+// false if key is already recorded, and returns a *HostKeyMismatchError if
+// a different key of the same type is.
+func appendKnownHost(path, hostname string, key ssh.PublicKey) (bool, error) {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	host, portStr, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return false, fmt.Errorf("failed to record host key for %s: %w", hostname, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return false, fmt.Errorf("failed to record host key for %s: %w", hostname, err)
+	}
+	entries, err := LookupKnownHost(path, host, port)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	var want []string
+	for _, e := range entries {
+		if e.Marker != "" || e.KeyType != key.Type() {
+			continue
+		}
+		if e.Fingerprint == fingerprint {
+			return false, nil
+		}
+		want = append(want, fmt.Sprintf("%s (%s:%d)", e.Fingerprint, path, e.Line))
+	}
+	if len(want) > 0 {
+		return false, &HostKeyMismatchError{
+			Host:        hostname,
+			Fingerprint: fingerprint,
+			Want:        want,
+			Source:      path,
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return false, fmt.Errorf("failed to record host key for %s: %w", hostname, err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return false, fmt.Errorf("failed to record host key for %s: %w", hostname, err)
+	}
+	return true, nil
+}
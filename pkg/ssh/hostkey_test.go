@@ -0,0 +1,298 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return key
+}
+
+func newTestECDSAHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return key
+}
+
+var testRemote = &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 22}
+
+// TestHostKeyCallback_UnknownHostRejected verifies that unknown hosts are
+// rejected unless trust-on-first-use is enabled
+func TestHostKeyCallback_UnknownHostRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+
+	callback, err := hostKeyCallback(ClientConfig{KnownHosts: path})
+	if err != nil {
+		t.Fatalf("hostKeyCallback should not fail: %v", err)
+	}
+
+	err = callback("192.168.1.10:22", testRemote, newTestHostKey(t))
+	var unknown *UnknownHostKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownHostKeyError, got: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("known_hosts should not be created without trust-on-first-use")
+	}
+}
+
+// TestHostKeyCallback_TrustOnFirstUse verifies that new keys are recorded and
+// that a changed key is rejected afterwards
+func TestHostKeyCallback_TrustOnFirstUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	key := newTestHostKey(t)
+
+	callback, err := hostKeyCallback(ClientConfig{KnownHosts: path, TrustOnFirstUse: true})
+	if err != nil {
+		t.Fatalf("hostKeyCallback should not fail: %v", err)
+	}
+	if err := callback("192.168.1.10:22", testRemote, key); err != nil {
+		t.Fatalf("first use should be trusted, got: %v", err)
+	}
+
+	entries, err := LookupKnownHost(path, "192.168.1.10", 22)
+	if err != nil {
+		t.Fatalf("LookupKnownHost should not fail: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Fatalf("expected the key to be recorded, got: %+v", entries)
+	}
+
+	callback, err = hostKeyCallback(ClientConfig{KnownHosts: path, TrustOnFirstUse: true})
+	if err != nil {
+		t.Fatalf("hostKeyCallback should not fail: %v", err)
+	}
+	if err := callback("192.168.1.10:22", testRemote, key); err != nil {
+		t.Errorf("recorded key should be accepted, got: %v", err)
+	}
+
+	err = callback("192.168.1.10:22", testRemote, newTestHostKey(t))
+	var mismatch *HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected HostKeyMismatchError, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "192.168.1.10") {
+		t.Errorf("mismatch error should name the host, got: %v", err)
+	}
+}
+
+// TestHostKeyCallback_PinnedKey verifies host_key pinning
+func TestHostKeyCallback_PinnedKey(t *testing.T) {
+	key := newTestHostKey(t)
+
+	callback, err := hostKeyCallback(ClientConfig{HostKey: string(ssh.MarshalAuthorizedKey(key))})
+	if err != nil {
+		t.Fatalf("hostKeyCallback should not fail: %v", err)
+	}
+	if err := callback("192.168.1.10:22", testRemote, key); err != nil {
+		t.Errorf("pinned key should be accepted, got: %v", err)
+	}
+
+	err = callback("192.168.1.10:22", testRemote, newTestHostKey(t))
+	var mismatch *HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected HostKeyMismatchError, got: %v", err)
+	}
+}
+
+// TestHostKeyCallback_UnknownKeyType verifies that a key of a type not
+// recorded for a known host is reported as unknown rather than as a
+// mismatch, and is not trusted on first use
+func TestHostKeyCallback_UnknownKeyType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if _, err := AddKnownHost(path, "192.168.1.10", 22, newTestHostKey(t)); err != nil {
+		t.Fatalf("AddKnownHost should not fail: %v", err)
+	}
+
+	callback, err := hostKeyCallback(ClientConfig{KnownHosts: path, TrustOnFirstUse: true})
+	if err != nil {
+		t.Fatalf("hostKeyCallback should not fail: %v", err)
+	}
+	err = callback("192.168.1.10:22", testRemote, newTestECDSAHostKey(t))
+	var unknown *UnknownHostKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownHostKeyError, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "unknown key type ecdsa-sha2-nistp256") {
+		t.Errorf("error should name the key type, got: %v", err)
+	}
+
+	entries, err := LookupKnownHost(path, "192.168.1.10", 22)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("the new key type should not be recorded, got: %+v, %v", entries, err)
+	}
+}
+
+// TestHostKeyAlgorithms verifies that only the recorded key types are
+// negotiated
+func TestHostKeyAlgorithms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	config := ClientConfig{Host: "192.168.1.10", Port: 22, KnownHosts: path}
+
+	if algorithms := hostKeyAlgorithms(config); algorithms != nil {
+		t.Errorf("expected no restriction for an unknown host, got: %v", algorithms)
+	}
+
+	if _, err := AddKnownHost(path, "192.168.1.10", 22, newTestECDSAHostKey(t)); err != nil {
+		t.Fatalf("AddKnownHost should not fail: %v", err)
+	}
+	if _, err := AddKnownHost(path, "192.168.1.11", 22, newTestHostKey(t)); err != nil {
+		t.Fatalf("AddKnownHost should not fail: %v", err)
+	}
+	want := []string{ssh.KeyAlgoECDSA256}
+	if algorithms := hostKeyAlgorithms(config); !reflect.DeepEqual(algorithms, want) {
+		t.Errorf("expected %v, got: %v", want, algorithms)
+	}
+
+	pinned := ClientConfig{HostKey: string(ssh.MarshalAuthorizedKey(newTestHostKey(t)))}
+	want = []string{ssh.KeyAlgoED25519}
+	if algorithms := hostKeyAlgorithms(pinned); !reflect.DeepEqual(algorithms, want) {
+		t.Errorf("expected %v for a pinned key, got: %v", want, algorithms)
+	}
+}
+
+// TestHostKeyCallback_TrustOnFirstUseConcurrent verifies that connections
+// racing to trust the same host record its key once
+func TestHostKeyCallback_TrustOnFirstUseConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	key := newTestHostKey(t)
+
+	var callbacks []ssh.HostKeyCallback
+	for i := 0; i < 8; i++ {
+		callback, err := hostKeyCallback(ClientConfig{KnownHosts: path, TrustOnFirstUse: true})
+		if err != nil {
+			t.Fatalf("hostKeyCallback should not fail: %v", err)
+		}
+		callbacks = append(callbacks, callback)
+	}
+
+	var wg sync.WaitGroup
+	for _, callback := range callbacks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := callback("192.168.1.10:22", testRemote, key); err != nil {
+				t.Errorf("first use should be trusted, got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := LookupKnownHost(path, "192.168.1.10", 22)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected the key to be recorded once, got: %+v, %v", entries, err)
+	}
+}
+
+// TestForgetHost verifies that only the forgotten host is removed
+func TestForgetHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	key := newTestHostKey(t)
+
+	if _, err := AddKnownHost(path, "192.168.1.10", 22, key); err != nil {
+		t.Fatalf("AddKnownHost should not fail: %v", err)
+	}
+	if _, err := AddKnownHost(path, "192.168.1.11", 2222, key); err != nil {
+		t.Fatalf("AddKnownHost should not fail: %v", err)
+	}
+
+	added, err := AddKnownHost(path, "192.168.1.10", 22, key)
+	if err != nil || added {
+		t.Errorf("adding the same key twice should be a no-op, got added=%v err=%v", added, err)
+	}
+
+	removed, err := ForgetHost(path, "192.168.1.10", 22)
+	if err != nil {
+		t.Fatalf("ForgetHost should not fail: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed entry, got: %d", removed)
+	}
+
+	entries, err := ListKnownHosts(path)
+	if err != nil {
+		t.Fatalf("ListKnownHosts should not fail: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hosts[0] != "[192.168.1.11]:2222" {
+		t.Errorf("expected only [192.168.1.11]:2222 to remain, got: %+v", entries)
+	}
+}
+
+// TestScanHostKey_Jump verifies that a host behind a jump host is scanned
+// through it
+func TestScanHostKey_Jump(t *testing.T) {
+	target := startTestServer(t, func(s *testSession) uint32 { return 0 })
+	bastion := startTestServer(t, func(s *testSession) uint32 { return 0 })
+	host, port := splitTestAddr(t, target)
+	bastionHost, bastionPort := splitTestAddr(t, bastion)
+
+	direct, err := ScanHostKey(context.Background(), ClientConfig{Host: host, Port: port})
+	if err != nil {
+		t.Fatalf("ScanHostKey should not fail: %v", err)
+	}
+
+	keyPath, _ := writeTestKey(t, "")
+	config := ClientConfig{
+		Host: host,
+		Port: port,
+		Jump: []ClientConfig{{
+			Host:            bastionHost,
+			Port:            bastionPort,
+			Username:        "test",
+			PrivateKey:      keyPath,
+			AuthOrder:       []string{AuthKey},
+			KnownHosts:      filepath.Join(t.TempDir(), "known_hosts"),
+			TrustOnFirstUse: true,
+		}},
+	}
+	forwards := testForwards.Load()
+	key, err := ScanHostKey(context.Background(), config)
+	if err != nil {
+		t.Fatalf("ScanHostKey through a jump host should not fail: %v", err)
+	}
+	if ssh.FingerprintSHA256(key) != ssh.FingerprintSHA256(direct) {
+		t.Errorf("expected %s, got: %s", ssh.FingerprintSHA256(direct), ssh.FingerprintSHA256(key))
+	}
+	if testForwards.Load() != forwards+1 {
+		t.Error("the host should be reached through the jump host")
+	}
+}
+
+func splitTestAddr(t *testing.T, addr string) (string, int) {
+	t.Helper()
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", addr, err)
+	}
+	return tcpAddr.IP.String(), tcpAddr.Port
+}
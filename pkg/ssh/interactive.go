@@ -0,0 +1,113 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// WindowSize is the size of a terminal in characters
+type WindowSize struct {
+	Width  int
+	Height int
+}
+
+// Terminal describes the pseudo-terminal requested for an interactive session
+type Terminal struct {
+	// Term is the TERM value, e.g. "xterm-256color"
+	Term string
+	Size WindowSize
+	// Resize delivers local window size changes to forward to the remote
+	// terminal
+	Resize <-chan WindowSize
+}
+
+// InteractiveOptions attaches an interactive session to local streams
+type InteractiveOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Terminal requests a pseudo-terminal when not nil. With a terminal the
+	// remote side merges stderr into stdout.
+	Terminal *Terminal
+	// Signals delivers signal names (e.g. "INT", "TERM") to forward to the
+	// remote process
+	Signals <-chan string
+}
+
+// Interactive runs cmd attached to the given streams, optionally on a
+// pseudo-terminal. Unlike Stream, it returns as soon as the remote command
+// exits, even if reading Stdin is still blocked. Failures are reported as
+// *ExitError.
+func (c *sshClient) Interactive(ctx context.Context, cmd string, opts InteractiveOptions) error {
+	session, exitErr := c.newSession(ctx, cmd)
+	if exitErr != nil {
+		return exitErr
+	}
+	defer session.Close()
+
+	if t := opts.Terminal; t != nil {
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty(t.Term, t.Size.Height, t.Size.Width, modes); err != nil {
+			return &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1,
+				Err: fmt.Errorf("failed to request pty: %w", err)}
+		}
+	}
+
+	session.Stdout = opts.Stdout
+	session.Stderr = opts.Stderr
+	if opts.Stdin != nil {
+		// Copy stdin ourselves: Session.Wait would otherwise block until the
+		// next read from a terminal returns after the command has exited.
+		stdin, err := session.StdinPipe()
+		if err != nil {
+			return &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1,
+				Err: fmt.Errorf("failed to open stdin: %w", err)}
+		}
+		go func() {
+			io.Copy(stdin, opts.Stdin)
+			stdin.Close()
+		}()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go forwardSessionEvents(session, opts, done)
+
+	if err := c.run(ctx, session, cmd); err != nil {
+		return err
+	}
+	return nil
+}
+
+func forwardSessionEvents(session *ssh.Session, opts InteractiveOptions, done <-chan struct{}) {
+	var resize <-chan WindowSize
+	if opts.Terminal != nil {
+		resize = opts.Terminal.Resize
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case size, ok := <-resize:
+			if !ok {
+				resize = nil
+				continue
+			}
+			session.WindowChange(size.Height, size.Width)
+		case sig, ok := <-opts.Signals:
+			if !ok {
+				opts.Signals = nil
+				continue
+			}
+			session.Signal(ssh.Signal(sig))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// bastion is a connection to a jump host shared by every target reached
+// through the same chain of hops.
+type bastion struct {
+	ready   chan struct{}
+	client  *ssh.Client
+	err     error
+	refs    int
+	release func()
+}
+
+var (
+	bastionsMu sync.Mutex
+	bastions   = map[string]*bastion{}
+)
+
+// acquireJumpChain returns a connection to the last hop of chain, dialing
+// each hop through the previous one. Connections are shared with other
+// callers using the same chain; the returned function releases this
+// caller's reference and closes the connection once it is unused.
+func acquireJumpChain(ctx context.Context, chain []ClientConfig) (*ssh.Client, func(), error) {
+	key := jumpChainKey(chain)
+
+	bastionsMu.Lock()
+	if b, ok := bastions[key]; ok {
+		b.refs++
+		bastionsMu.Unlock()
+
+		select {
+		case <-b.ready:
+		case <-ctx.Done():
+			// Give the reference back once the other caller is done dialing
+			go func() {
+				<-b.ready
+				if b.err == nil {
+					releaseBastion(key, b)
+				}
+			}()
+			return nil, nil, fmt.Errorf("jump host %s: %w", chain[len(chain)-1].Host, ctx.Err())
+		}
+		if b.err != nil {
+			return nil, nil, b.err
+		}
+		return b.client, func() { releaseBastion(key, b) }, nil
+	}
+	b := &bastion{ready: make(chan struct{}), refs: 1}
+	bastions[key] = b
+	bastionsMu.Unlock()
+
+	b.client, b.release, b.err = dialJumpHop(ctx, chain)
+	close(b.ready)
+
+	if b.err != nil {
+		bastionsMu.Lock()
+		if bastions[key] == b {
+			delete(bastions, key)
+		}
+		bastionsMu.Unlock()
+		return nil, nil, b.err
+	}
+	return b.client, func() { releaseBastion(key, b) }, nil
+}
+
+func dialJumpHop(ctx context.Context, chain []ClientConfig) (*ssh.Client, func(), error) {
+	hop := chain[len(chain)-1]
+	if hop.Port == 0 {
+		hop.Port = 22
+	}
+
+	var via *ssh.Client
+	release := func() {}
+	if len(chain) > 1 {
+		var err error
+		via, release, err = acquireJumpChain(ctx, chain[:len(chain)-1])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client, err := dial(ctx, hop, via)
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("jump host %s: %w", hop.Host, err)
+	}
+	return client, release, nil
+}
+
+func releaseBastion(key string, b *bastion) {
+	bastionsMu.Lock()
+	b.refs--
+	if b.refs > 0 {
+		bastionsMu.Unlock()
+		return
+	}
+	if bastions[key] == b {
+		delete(bastions, key)
+	}
+	bastionsMu.Unlock()
+
+	b.client.Close()
+	b.release()
+}
+
+func jumpChainKey(chain []ClientConfig) string {
+	hops := make([]string, len(chain))
+	for i, hop := range chain {
+		port := hop.Port
+		if port == 0 {
+			port = 22
+		}
+		hops[i] = fmt.Sprintf("%s@%s:%d", hop.Username, hop.Host, port)
+	}
+	return strings.Join(hops, ">")
+}
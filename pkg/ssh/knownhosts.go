@@ -0,0 +1,243 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHost is a single key entry from a known_hosts file
+type KnownHost struct {
+	Hosts       []string
+	KeyType     string
+	Fingerprint string
+	Marker      string
+	Line        int
+}
+
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanHostKey connects to config.Host, through its jump chain if any, and
+// returns the host key it presents without authenticating or verifying it.
+// Jump hosts are verified and authenticated as for NewClient.
+func ScanHostKey(ctx context.Context, config ClientConfig) (ssh.PublicKey, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+	}
+	addr := joinHostPort(config.Host, config.Port)
+
+	var conn net.Conn
+	if len(config.Jump) > 0 {
+		via, release, err := acquireJumpChain(ctx, config.Jump)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		if conn, err = via.DialContext(ctx, "tcp", addr); err != nil {
+			return nil, fmt.Errorf("failed to dial through jump host: %w", err)
+		}
+	} else {
+		var dialer net.Dialer
+		var err error
+		if conn, err = dialer.DialContext(ctx, "tcp", addr); err != nil {
+			return nil, fmt.Errorf("failed to dial: %w", err)
+		}
+	}
+	defer conn.Close()
+
+	// Tunnelled connections do not support deadlines; closing the
+	// connection interrupts the handshake
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var hostKey ssh.PublicKey
+	_, _, _, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyScanned
+		},
+	})
+	if hostKey == nil {
+		return nil, fmt.Errorf("failed to read host key: %w", err)
+	}
+	return hostKey, nil
+}
+
+// AddKnownHost records key for host:port in the known_hosts file at path.
+// It reports false if the same key was already recorded for the host, and
+// returns a *HostKeyMismatchError if a different key of the same type is
+// recorded. Keys of other types are kept alongside.
+func AddKnownHost(path, host string, port int, key ssh.PublicKey) (bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := createKnownHostsFile(path); err != nil {
+			return false, err
+		}
+	}
+	return appendKnownHost(path, joinHostPort(host, port), key)
+}
+
+// ListKnownHosts returns every key entry in the known_hosts file at path
+func ListKnownHosts(path string) ([]KnownHost, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []KnownHost
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		marker, hosts, key, _, _, err := ssh.ParseKnownHosts(scanner.Bytes())
+		if err != nil {
+			// ParseKnownHosts returns io.EOF for blank and comment lines
+			continue
+		}
+		entries = append(entries, KnownHost{
+			Hosts:       hosts,
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Marker:      marker,
+			Line:        lineNum,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// LookupKnownHost returns the known_hosts entries that apply to host:port,
+// including entries stored with hashed host names.
+func LookupKnownHost(path, host string, port int) ([]KnownHost, error) {
+	entries, err := ListKnownHosts(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := knownhosts.Normalize(joinHostPort(host, port))
+	var matched []KnownHost
+	for _, e := range entries {
+		for _, pattern := range e.Hosts {
+			if hostPatternEquals(pattern, name) {
+				matched = append(matched, e)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// ForgetHost removes host:port from the known_hosts file at path and
+// returns the number of entries it was removed from. Lines listing several
+// hosts keep the remaining ones.
+func ForgetHost(path, host string, port int) (int, error) {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	name := knownhosts.Normalize(joinHostPort(host, port))
+	lines := strings.SplitAfter(string(data), "\n")
+	removed := 0
+	var out strings.Builder
+	for _, line := range lines {
+		rewritten, ok := forgetInLine(line, name)
+		if ok {
+			removed++
+		}
+		out.WriteString(rewritten)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(path, []byte(out.String()), 0600); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return removed, nil
+}
+
+// forgetInLine drops name from the host list of a known_hosts line. It
+// returns the line to keep (possibly empty) and whether name was found.
+func forgetInLine(line, name string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return line, false
+	}
+
+	fields := strings.Fields(trimmed)
+	hostIdx := 0
+	if strings.HasPrefix(fields[0], "@") {
+		hostIdx = 1
+	}
+	if len(fields) <= hostIdx {
+		return line, false
+	}
+
+	var kept []string
+	found := false
+	for _, pattern := range strings.Split(fields[hostIdx], ",") {
+		if hostPatternEquals(pattern, name) {
+			found = true
+			continue
+		}
+		kept = append(kept, pattern)
+	}
+	if !found {
+		return line, false
+	}
+	if len(kept) == 0 {
+		return "", true
+	}
+
+	fields[hostIdx] = strings.Join(kept, ",")
+	return strings.Join(fields, " ") + "\n", true
+}
+
+// hostPatternEquals reports whether a known_hosts host pattern names exactly
+// the normalized address, either literally or as a hashed entry.
+func hostPatternEquals(pattern, name string) bool {
+	if !strings.HasPrefix(pattern, "|1|") {
+		return pattern == name
+	}
+
+	parts := strings.Split(pattern[3:], "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+func joinHostPort(host string, port int) string {
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host, fmt.Sprint(port))
+}
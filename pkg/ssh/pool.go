@@ -0,0 +1,276 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Defaults for PoolOptions
+const (
+	// DefaultMaxSessions matches the MaxSessions default of OpenSSH's sshd
+	DefaultMaxSessions = 10
+	DefaultKeepAlive   = 30 * time.Second
+)
+
+// PoolOptions configures a Pool
+type PoolOptions struct {
+	// MaxSessions limits the concurrent sessions on one host's connection.
+	// Defaults to DefaultMaxSessions.
+	MaxSessions int
+	// KeepAlive is the interval between keepalive requests. A connection
+	// that fails to answer is replaced on next use. Defaults to
+	// DefaultKeepAlive; a negative value disables keepalives.
+	KeepAlive time.Duration
+}
+
+// Pool shares one SSH connection per host between all clients it hands
+// out. Each command runs in its own session on the shared connection;
+// broken connections are re-established transparently.
+type Pool struct {
+	opts PoolOptions
+
+	mu     sync.Mutex
+	conns  map[string]*pooledConn
+	closed bool
+}
+
+// NewPool creates an empty connection pool
+func NewPool(opts PoolOptions) *Pool {
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = DefaultMaxSessions
+	}
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	return &Pool{
+		opts:  opts,
+		conns: map[string]*pooledConn{},
+	}
+}
+
+var errPoolClosed = errors.New("connection pool is closed")
+
+// Get returns a client for config. The connection is established on first
+// use and shared with every other client for the same host. Closing the
+// returned client does not close the shared connection; use Pool.Close.
+// ctx bounds connecting only.
+func (p *Pool) Get(ctx context.Context, config ClientConfig) (Client, error) {
+	key := jumpChainKey(append(append([]ClientConfig{}, config.Jump...), config))
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	conn, ok := p.conns[key]
+	if !ok {
+		conn = &pooledConn{
+			host:      hostAddr(config),
+			sessions:  make(chan struct{}, p.opts.MaxSessions),
+			keepAlive: p.opts.KeepAlive,
+			dial: func(ctx context.Context) (*ssh.Client, func(), error) {
+				return connectClient(ctx, config)
+			},
+		}
+		p.conns[key] = conn
+	}
+	p.mu.Unlock()
+
+	// Connect eagerly so that connection errors surface where callers
+	// expect them, as with NewClient
+	if _, err := conn.get(ctx); err != nil {
+		return nil, err
+	}
+	return &pooledClient{conn: conn}, nil
+}
+
+// Close closes every pooled connection
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = map[string]*pooledConn{}
+	p.closed = true
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, conn := range conns {
+		if err := conn.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// pooledConn is the shared connection to one host
+type pooledConn struct {
+	host      string
+	sessions  chan struct{}
+	keepAlive time.Duration
+	dial      func(ctx context.Context) (*ssh.Client, func(), error)
+
+	mu      sync.Mutex
+	client  *sshClient
+	stopped chan struct{}
+	closed  bool
+}
+
+// get returns the current connection, dialing a new one if there is none
+// or the previous one broke
+func (c *pooledConn) get(ctx context.Context) (*sshClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errPoolClosed
+	}
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	client, release, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.client = &sshClient{client: client, host: c.host, release: release}
+	c.stopped = make(chan struct{})
+	go c.watch(c.client, c.stopped)
+	return c.client, nil
+}
+
+// discard drops client if it is still the current connection
+func (c *pooledConn) discard(client *sshClient) {
+	c.mu.Lock()
+	if c.client != client {
+		c.mu.Unlock()
+		return
+	}
+	c.client = nil
+	close(c.stopped)
+	c.mu.Unlock()
+
+	client.Close()
+}
+
+func (c *pooledConn) close() error {
+	c.mu.Lock()
+	c.closed = true
+	client := c.client
+	if client != nil {
+		c.client = nil
+		close(c.stopped)
+	}
+	c.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
+// watch discards client when the connection drops or stops answering
+// keepalive requests
+func (c *pooledConn) watch(client *sshClient, stopped <-chan struct{}) {
+	dead := make(chan struct{})
+	go func() {
+		client.client.Wait()
+		close(dead)
+	}()
+
+	var tick <-chan time.Time
+	if c.keepAlive > 0 {
+		ticker := time.NewTicker(c.keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-dead:
+			c.discard(client)
+			return
+		case <-tick:
+			if _, _, err := client.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				c.discard(client)
+				return
+			}
+		}
+	}
+}
+
+// acquire reserves one of the host's session slots
+func (c *pooledConn) acquire(ctx context.Context, cmd string) (func(), error) {
+	select {
+	case c.sessions <- struct{}{}:
+		return func() { <-c.sessions }, nil
+	case <-ctx.Done():
+		return nil, contextError(ctx, c.host, cmd, "", "")
+	}
+}
+
+// do runs fn on the shared connection within a session slot. If no session
+// could be opened, the connection is assumed broken and fn is retried once
+// on a new connection.
+func (c *pooledConn) do(ctx context.Context, cmd string, fn func(*sshClient) error) error {
+	release, err := c.acquire(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for attempt := 0; ; attempt++ {
+		client, err := c.get(ctx)
+		if err != nil {
+			return &ExitError{Kind: ConnectionFailure, Host: c.host, Command: cmd, ExitCode: -1, Err: err}
+		}
+
+		err = fn(client)
+		var exitErr *ExitError
+		if attempt == 0 && errors.As(err, &exitErr) && exitErr.sessionFailed {
+			c.discard(client)
+			continue
+		}
+		return err
+	}
+}
+
+// pooledClient runs commands on a pooled connection
+type pooledClient struct {
+	conn *pooledConn
+}
+
+func (c *pooledClient) Execute(ctx context.Context, cmd string) (string, error) {
+	var output string
+	err := c.conn.do(ctx, cmd, func(client *sshClient) error {
+		var err error
+		output, err = client.Execute(ctx, cmd)
+		return err
+	})
+	return output, err
+}
+
+func (c *pooledClient) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return c.conn.do(ctx, cmd, func(client *sshClient) error {
+		return client.Stream(ctx, cmd, stdin, stdout, stderr)
+	})
+}
+
+func (c *pooledClient) Interactive(ctx context.Context, cmd string, opts InteractiveOptions) error {
+	return c.conn.do(ctx, cmd, func(client *sshClient) error {
+		return client.Interactive(ctx, cmd, opts)
+	})
+}
+
+// Close is a no-op: the connection stays open for other clients until the
+// pool is closed
+func (c *pooledClient) Close() error {
+	return nil
+}
+
+var _ Client = (*pooledClient)(nil)
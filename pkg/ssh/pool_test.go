@@ -0,0 +1,103 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestPooledConn returns a pooledConn dialing a test server and counting
+// the connections it makes
+func newTestPooledConn(t *testing.T, maxSessions int, handler testExecHandler) (*pooledConn, *int32) {
+	t.Helper()
+	addr := startTestServer(t, handler)
+
+	var dials int32
+	conn := &pooledConn{
+		host:     addr,
+		sessions: make(chan struct{}, maxSessions),
+		dial: func(ctx context.Context) (*ssh.Client, func(), error) {
+			atomic.AddInt32(&dials, 1)
+			client, err := dialTestServer(addr)
+			return client, func() {}, err
+		},
+	}
+	t.Cleanup(func() { conn.close() })
+	return conn, &dials
+}
+
+// TestPool_SharesConnection verifies that commands reuse one connection
+func TestPool_SharesConnection(t *testing.T) {
+	conn, dials := newTestPooledConn(t, 10, func(s *testSession) uint32 {
+		s.Channel.Write([]byte("ok"))
+		return 0
+	})
+	client := &pooledClient{conn: conn}
+
+	for i := 0; i < 5; i++ {
+		output, err := client.Execute(context.Background(), "podman ps")
+		if err != nil || output != "ok" {
+			t.Fatalf("Execute should succeed, got: %q, %v", output, err)
+		}
+	}
+	if *dials != 1 {
+		t.Errorf("expected 1 connection, got: %d", *dials)
+	}
+}
+
+// TestPool_Reconnects verifies that a broken connection is replaced
+func TestPool_Reconnects(t *testing.T) {
+	conn, dials := newTestPooledConn(t, 10, func(s *testSession) uint32 {
+		return 0
+	})
+	client := &pooledClient{conn: conn}
+
+	if _, err := client.Execute(context.Background(), "true"); err != nil {
+		t.Fatalf("Execute should succeed, got: %v", err)
+	}
+
+	// Break the connection behind the pool's back
+	conn.client.client.Close()
+
+	if _, err := client.Execute(context.Background(), "true"); err != nil {
+		t.Fatalf("Execute should reconnect, got: %v", err)
+	}
+	if *dials != 2 {
+		t.Errorf("expected 2 connections, got: %d", *dials)
+	}
+}
+
+// TestPool_MaxSessions verifies that sessions beyond the limit wait for a
+// free slot
+func TestPool_MaxSessions(t *testing.T) {
+	release := make(chan struct{})
+	conn, _ := newTestPooledConn(t, 1, func(s *testSession) uint32 {
+		<-release
+		return 0
+	})
+	client := &pooledClient{conn: conn}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Execute(context.Background(), "sleep")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.Execute(ctx, "podman ps")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Kind != Timeout {
+		t.Errorf("second session should wait for a slot and time out, got: %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("first session should succeed, got: %v", err)
+	}
+}
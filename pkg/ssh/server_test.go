@@ -0,0 +1,210 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSession is the server side of an exec request handled by a test server
+type testSession struct {
+	Command string
+	Channel ssh.Channel
+	// Signals receives the names of signals sent by the client
+	Signals <-chan string
+}
+
+// testExecHandler runs a command on the test server and returns its exit
+// status
+type testExecHandler func(s *testSession) uint32
+
+// newTestServer starts an in-process SSH server that accepts any client and
+// hands exec requests to handler. It returns a connected sshClient.
+func newTestServer(t *testing.T, handler testExecHandler) *sshClient {
+	t.Helper()
+
+	addr := startTestServer(t, handler)
+	client, err := dialTestServer(addr)
+	if err != nil {
+		t.Fatalf("failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return &sshClient{client: client, host: addr}
+}
+
+// startTestServer starts an in-process SSH server that accepts any client
+// and returns its address
+func startTestServer(t *testing.T, handler testExecHandler) string {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("failed to create host key signer: %v", err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn, serverConfig, handler)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func dialTestServer(addr string) (*ssh.Client, error) {
+	return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+}
+
+func serveTestConn(conn net.Conn, config *ssh.ServerConfig, handler testExecHandler) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() == "direct-streamlocal@openssh.com" {
+			serveTestSocket(newChannel)
+			continue
+		}
+		if newChannel.ChannelType() == "direct-tcpip" {
+			serveTestForward(newChannel)
+			continue
+		}
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go serveTestSession(channel, requests, handler)
+	}
+}
+
+// testSocketPath is the only unix socket the test server accepts
+// connections to. It echoes what it receives.
+const testSocketPath = "/run/test.sock"
+
+func serveTestSocket(newChannel ssh.NewChannel) {
+	var payload struct {
+		SocketPath string
+		Reserved0  string
+		Reserved1  uint32
+	}
+	ssh.Unmarshal(newChannel.ExtraData(), &payload)
+	if payload.SocketPath != testSocketPath {
+		newChannel.Reject(ssh.ConnectionFailed, "no such file or directory")
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		defer channel.Close()
+		io.Copy(channel, channel)
+	}()
+}
+
+// testForwards counts the direct-tcpip connections made through test
+// servers acting as jump hosts
+var testForwards atomic.Int32
+
+func serveTestForward(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	ssh.Unmarshal(newChannel.ExtraData(), &payload)
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, fmt.Sprint(payload.Port)))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	testForwards.Add(1)
+	go ssh.DiscardRequests(requests)
+	go func() {
+		defer channel.Close()
+		io.Copy(channel, conn)
+	}()
+	go func() {
+		defer conn.Close()
+		io.Copy(conn, channel)
+	}()
+}
+
+func serveTestSession(channel ssh.Channel, requests <-chan *ssh.Request, handler testExecHandler) {
+	defer channel.Close()
+
+	signals := make(chan string, 4)
+	exec := make(chan string, 1)
+	go func() {
+		for req := range requests {
+			switch req.Type {
+			case "exec":
+				var payload struct{ Command string }
+				ssh.Unmarshal(req.Payload, &payload)
+				req.Reply(true, nil)
+				exec <- payload.Command
+			case "signal":
+				var payload struct{ Signal string }
+				ssh.Unmarshal(req.Payload, &payload)
+				select {
+				case signals <- payload.Signal:
+				default:
+				}
+			default:
+				req.Reply(req.Type == "pty-req" || req.Type == "window-change", nil)
+			}
+		}
+		close(signals)
+		close(exec)
+	}()
+
+	cmd, ok := <-exec
+	if !ok {
+		return
+	}
+	status := handler(&testSession{Command: cmd, Channel: channel, Signals: signals})
+
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, status)
+	channel.SendRequest("exit-status", false, payload)
+}
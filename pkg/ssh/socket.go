@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SocketDialer is implemented by clients that can connect to unix sockets on
+// the remote host, tunnelled through the SSH connection with OpenSSH's
+// direct-streamlocal extension
+type SocketDialer interface {
+	DialSocket(ctx context.Context, path string) (net.Conn, error)
+}
+
+// DialSocket connects to the unix socket at path on the remote host. The
+// server must allow stream local forwarding (AllowStreamLocalForwarding in
+// sshd_config, enabled by default).
+func (c *sshClient) DialSocket(ctx context.Context, path string) (net.Conn, error) {
+	conn, err := c.client.DialContext(ctx, "unix", path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx, c.host, path, "", "")
+		}
+		return nil, socketError(c.host, path, err)
+	}
+	return conn, nil
+}
+
+// DialSocket connects to the unix socket at path over the shared connection.
+// Tunnelled connections are not sessions and do not take a session slot. If
+// the connection turns out to be broken, it is replaced and the dial retried
+// once.
+func (c *pooledClient) DialSocket(ctx context.Context, path string) (net.Conn, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := c.conn.get(ctx)
+		if err != nil {
+			return nil, &ExitError{Kind: ConnectionFailure, Host: c.conn.host, Command: path, ExitCode: -1, Err: err}
+		}
+
+		conn, err := client.DialSocket(ctx, path)
+		var exitErr *ExitError
+		if attempt == 0 && errors.As(err, &exitErr) && exitErr.sessionFailed {
+			c.conn.discard(client)
+			continue
+		}
+		return conn, err
+	}
+}
+
+// socketError builds the ExitError for a failed dial. A rejected channel
+// means the server is alive but could not connect to the socket; any other
+// failure is treated like a session that could not be opened.
+func socketError(host, path string, err error) *ExitError {
+	var rejected *ssh.OpenChannelError
+	if errors.As(err, &rejected) {
+		return &ExitError{Kind: ConnectionFailure, Host: host, Command: path, ExitCode: -1,
+			Err: fmt.Errorf("failed to connect to %s: %s", path, rejected.Message)}
+	}
+	return &ExitError{Kind: ConnectionFailure, Host: host, Command: path, ExitCode: -1,
+		Err: fmt.Errorf("failed to connect to %s: %w", path, err), sessionFailed: true}
+}
+
+var (
+	_ SocketDialer = (*sshClient)(nil)
+	_ SocketDialer = (*pooledClient)(nil)
+)